@@ -4,6 +4,7 @@ import (
 	"go-flashcards-server/pkg/config"
 	"go-flashcards-server/pkg/db"
 	"go-flashcards-server/pkg/handler"
+	"go-flashcards-server/pkg/jobs"
 	"go-flashcards-server/pkg/middleware"
 	"log"
 	"net/http"
@@ -14,6 +15,10 @@ import (
 func main() {
     config.Init()
     db.Init()
+    jobs.Start(config.JobWorkers)
+    jobs.StartPurge(config.TrashRetentionDays)
+    store := db.SQLStore{}
+    handler.UseStore(store)
 
     r := mux.NewRouter()
     r.Use(middleware.CorsMiddleware)
@@ -21,20 +26,66 @@ func main() {
     r.HandleFunc("/signup", handler.SignUp).Methods("POST", "OPTIONS")
     r.HandleFunc("/login", handler.Login).Methods("POST", "OPTIONS")
 
-    deckRouter := r.PathPrefix("/deck").Subrouter()
-    deckRouter.Use(middleware.AuthMiddleware)
-    deckRouter.HandleFunc("/create", handler.CreateDeck).Methods("POST")
-    deckRouter.HandleFunc("", handler.GetDecks).Methods("GET", "OPTIONS")
-    deckRouter.HandleFunc("/update/{deck_id}", handler.UpdateDeck).Methods("PUT")
-    deckRouter.HandleFunc("/delete/{deck_id}", handler.DeleteDeck).Methods("DELETE")
-
-    cardRouter := r.PathPrefix("/card").Subrouter()
-    cardRouter.Use(middleware.AuthMiddleware)
-    cardRouter.HandleFunc("/create", handler.CreateCard).Methods("POST")
-    cardRouter.HandleFunc("/{deck_id}", handler.GetCardsByDeck).Methods("GET")
-    cardRouter.HandleFunc("/update/{card_id}", handler.UpdateCard).Methods("PUT")
-    cardRouter.HandleFunc("/delete/{card_id}", handler.DeleteCard).Methods("DELETE")
-    
+    handler.RegisterDeckRoutes(r)
+
+    classRouter := r.PathPrefix("/class").Subrouter()
+    classRouter.Use(middleware.AuthMiddleware)
+    classRouter.HandleFunc("/create", handler.CreateClassroom).Methods("POST")
+    classRouter.HandleFunc("", handler.GetClassrooms).Methods("GET", "OPTIONS")
+    classRouter.HandleFunc("/join", handler.JoinClassroom).Methods("POST")
+    classRouter.HandleFunc("/{class_id}/member/{user_id}", handler.RemoveClassroomMember).Methods("DELETE")
+    classRouter.HandleFunc("/{class_id}/assign", handler.AssignDeck).Methods("POST")
+    classRouter.HandleFunc("/{class_id}/assign/{deck_id}", handler.UnassignDeck).Methods("DELETE")
+    classRouter.HandleFunc("/{class_id}/assignments", handler.GetAssignments).Methods("GET")
+    classRouter.HandleFunc("/{class_id}/progress", handler.GetClassroomProgress).Methods("GET")
+
+    groupRouter := r.PathPrefix("/group").Subrouter()
+    groupRouter.Use(middleware.AuthMiddleware)
+    groupRouter.HandleFunc("/create", handler.CreateGroup).Methods("POST")
+    groupRouter.HandleFunc("", handler.GetGroups).Methods("GET", "OPTIONS")
+    groupRouter.HandleFunc("/join", handler.JoinGroup).Methods("POST")
+    groupRouter.HandleFunc("/invites", handler.GetGroupInvites).Methods("GET")
+    groupRouter.HandleFunc("/invites/{group_id}", handler.AcceptGroupInvite).Methods("POST")
+    groupRouter.HandleFunc("/invites/{group_id}", handler.DeclineGroupInvite).Methods("DELETE")
+    groupRouter.HandleFunc("/{group_id}/invite", handler.InviteToGroup).Methods("POST")
+    groupRouter.HandleFunc("/{group_id}/visibility", handler.SetGroupVisibility).Methods("PUT")
+    groupRouter.HandleFunc("/{group_id}/leave", handler.LeaveGroup).Methods("DELETE")
+    groupRouter.HandleFunc("/{group_id}/leaderboard", handler.GetLeaderboard).Methods("GET")
+    groupRouter.HandleFunc("/{group_id}/challenge", handler.CreateChallenge).Methods("POST")
+    groupRouter.HandleFunc("/{group_id}/challenges", handler.GetChallenges).Methods("GET")
+
+    importRouter := r.PathPrefix("/import").Subrouter()
+    importRouter.Use(middleware.AuthMiddleware)
+    importRouter.HandleFunc("/apkg", handler.ImportAnki).Methods("POST")
+    importRouter.HandleFunc("/csv", handler.PreviewCSVImport).Methods("POST")
+    importRouter.HandleFunc("/csv/{upload_id}", handler.ConfirmCSVImport).Methods("POST")
+    importRouter.HandleFunc("/markdown", handler.ImportMarkdown).Methods("POST")
+    importRouter.HandleFunc("/quizlet", handler.ImportQuizlet).Methods("POST")
+    importRouter.HandleFunc("/supermemo", handler.ImportSuperMemo).Methods("POST")
+
+    jobRouter := r.PathPrefix("/jobs").Subrouter()
+    jobRouter.Use(middleware.AuthMiddleware)
+    jobRouter.HandleFunc("", handler.GetJobs).Methods("GET")
+    jobRouter.HandleFunc("/import", handler.EnqueueImport).Methods("POST")
+    jobRouter.HandleFunc("/export", handler.EnqueueExport).Methods("POST")
+    jobRouter.HandleFunc("/{job_id}", handler.GetJob).Methods("GET")
+    jobRouter.HandleFunc("/{job_id}/artifact", handler.GetJobArtifact).Methods("GET")
+
+    trashRouter := r.PathPrefix("/trash").Subrouter()
+    trashRouter.Use(middleware.AuthMiddleware)
+    trashRouter.HandleFunc("", handler.GetTrash).Methods("GET")
+    trashRouter.HandleFunc("/deck/{deck_id}/restore", handler.RestoreDeck).Methods("POST")
+    trashRouter.HandleFunc("/card/{card_id}/restore", handler.RestoreCard).Methods("POST")
+
+    liveRouter := r.PathPrefix("/live").Subrouter()
+    liveRouter.Handle("/create", middleware.AuthMiddleware(http.HandlerFunc(handler.CreateLiveGame))).Methods("POST")
+    liveRouter.Handle("/{pin}/host", middleware.AuthMiddleware(http.HandlerFunc(handler.HostLiveGame))).Methods("GET")
+    liveRouter.HandleFunc("/{pin}/play", handler.JoinLiveGame).Methods("GET")
+
+    r.HandleFunc("/catalog", handler.GetCatalog).Methods("GET", "OPTIONS")
+    r.HandleFunc("/catalog/{catalog_id}", handler.GetCatalogDeck).Methods("GET", "OPTIONS")
+    r.Handle("/catalog/{catalog_id}/clone", middleware.AuthMiddleware(http.HandlerFunc(handler.CloneCatalogDeck))).Methods("POST")
+
     log.Println("Server started on :8000")
     log.Fatal(http.ListenAndServe(":8000", r))
 }
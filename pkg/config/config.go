@@ -3,16 +3,22 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
 
 var JWTSecretKey []byte
+var DBDriver string
+var DBPath string
 var DBName string
 var DBUsername string
 var DBPassword string
 var DBHost string
 var DBPort string
+var DBSSLMode string
+var JobWorkers int
+var TrashRetentionDays int
 
 func Init() {
 	err := godotenv.Load()
@@ -25,12 +31,48 @@ func Init() {
 	}
 	JWTSecretKey = []byte(secret)
 
-	DBName = os.Getenv("DB_NAME")
-	DBUsername = os.Getenv("DB_USERNAME")
-	DBPassword= os.Getenv("DB_PASSWORD")
-	DBHost = os.Getenv("DB_HOST")
-	DBPort = os.Getenv("DB_PORT")
-	if DBUsername == "" || DBPassword == "" || DBHost == "" || DBPort == ""  || DBName == "" {
-        log.Fatal("Missing one or more required environment variables")
-    }
+	DBDriver = os.Getenv("DB_DRIVER")
+	if DBDriver == "" {
+		DBDriver = "mysql"
+	}
+	switch DBDriver {
+	case "mysql", "postgres":
+		DBName = os.Getenv("DB_NAME")
+		DBUsername = os.Getenv("DB_USERNAME")
+		DBPassword= os.Getenv("DB_PASSWORD")
+		DBHost = os.Getenv("DB_HOST")
+		DBPort = os.Getenv("DB_PORT")
+		if DBUsername == "" || DBPassword == "" || DBHost == "" || DBPort == ""  || DBName == "" {
+			log.Fatal("Missing one or more required environment variables")
+		}
+		DBSSLMode = os.Getenv("DB_SSLMODE")
+		if DBDriver == "postgres" && DBSSLMode == "" {
+			DBSSLMode = "disable"
+		}
+	case "sqlite":
+		DBPath = os.Getenv("DB_PATH")
+		if DBPath == "" {
+			DBPath = "flashcards.db"
+		}
+	default:
+		log.Fatal("DB_DRIVER must be mysql, sqlite or postgres")
+	}
+
+	JobWorkers = 2
+	if workers := os.Getenv("JOB_WORKERS"); workers != "" {
+		n, err := strconv.Atoi(workers)
+		if err != nil || n < 1 {
+			log.Fatal("JOB_WORKERS must be a positive number")
+		}
+		JobWorkers = n
+	}
+
+	TrashRetentionDays = 30
+	if days := os.Getenv("TRASH_RETENTION_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			log.Fatal("TRASH_RETENTION_DAYS must be a positive number")
+		}
+		TrashRetentionDays = n
+	}
 }
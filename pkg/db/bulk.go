@@ -0,0 +1,77 @@
+package db
+
+import (
+	"fmt"
+	"log"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// ApplyBulk runs card operations for userID in one transaction. Operations
+// must already be validated and authorized; any database error rolls back all
+// of them.
+func ApplyBulk(userID int, ops []types.BulkOperation) ([]types.BulkResult, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]types.BulkResult, len(ops))
+	for i, op := range ops {
+		result := types.BulkResult{Index: i, Op: op.Op, OK: true, CardID: op.CardID}
+		switch op.Op {
+		case types.BulkCreate:
+			cardID, err := insertCard(tx, op.DeckID, op.Question, op.Answer, op.Tags)
+			if err != nil {
+				return nil, err
+			}
+			result.CardID = int(cardID)
+		case types.BulkUpdate:
+			if _, err := updateCard(tx, op.CardID, userID, op.Question, op.Answer); err != nil {
+				return nil, err
+			}
+			if op.Tags != nil {
+				if err := replaceCardTags(tx, op.CardID, op.Tags); err != nil {
+					return nil, err
+				}
+			}
+		case types.BulkDelete:
+			if _, err := trashCard(tx, op.CardID); err != nil {
+				return nil, err
+			}
+		case types.BulkMove:
+			if err := moveCard(tx, op.CardID, op.DeckID); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown bulk operation %q", op.Op)
+		}
+		results[i] = result
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing bulk operations: %v", err)
+		return nil, err
+	}
+	return results, nil
+}
+
+// moveCard moves a card to the end of another deck. Its link to an upstream
+// card is dropped since the card no longer belongs to the forked deck.
+func moveCard(q querier, cardID, deckID int) error {
+	position, err := cardList(deckID).next(q)
+	if err != nil {
+		return err
+	}
+	if _, err := q.Exec("UPDATE card SET deck_id = ?, position = ? WHERE id = ?", deckID, position, cardID); err != nil {
+		log.Printf("Error moving card %d to deck %d: %v", cardID, deckID, err)
+		return err
+	}
+	if _, err := q.Exec("UPDATE card_fork SET card_id = NULL WHERE card_id = ?", cardID); err != nil {
+		log.Printf("Error unlinking card %d: %v", cardID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"log"
+
+	"go-flashcards-server/pkg/types"
+)
+
+func GetCardsByDeck(deckID int) ([]types.Card, error) {
+	return loadDeckCards(DB, deckID)
+}
+
+// loadDeckCards returns a deck's cards in their order.
+func loadDeckCards(q querier, deckID int) ([]types.Card, error) {
+	query := "SELECT id, deck_id, question, answer, created_at, position FROM card WHERE deck_id = ? AND deleted_at IS NULL ORDER BY position, id"
+	rows, err := q.Query(query, deckID)
+	if err != nil {
+		log.Printf("Error retrieving cards for deck %d: %v", deckID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []types.Card{}
+	for rows.Next() {
+		var card types.Card
+		if err := rows.Scan(&card.ID, &card.DeckID, &card.Question, &card.Answer, &card.CreatedAt, &card.Position); err != nil {
+			log.Printf("Error scanning card row: %v", err)
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+// insertCard is the single place cards are inserted, so handlers and bulk
+// imports create cards the same way.
+func insertCard(q querier, deckID int, question, answer string, tags []string) (int64, error) {
+	position, err := cardList(deckID).next(q)
+	if err != nil {
+		return 0, err
+	}
+	cardID, err := insertID(q, "INSERT INTO card (deck_id, question, answer, position) VALUES (?, ?, ?, ?)",
+		deckID, question, answer, position)
+	if err != nil {
+		log.Printf("Error creating card in deck %d: %v", deckID, err)
+		return 0, err
+	}
+	for _, tag := range tags {
+		if _, err := q.Exec(sqlDialect.ignore("INSERT INTO card_tag (card_id, tag) VALUES (?, ?)"), cardID, tag); err != nil {
+			log.Printf("Error tagging card %d: %v", cardID, err)
+			return 0, err
+		}
+	}
+	return cardID, nil
+}
+
+func CreateCard(deckID int, question, answer string, tags []string) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	cardID, err := insertCard(tx, deckID, question, answer, tags)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing card: %v", err)
+		return 0, err
+	}
+	return cardID, nil
+}
+
+// GetCardTags returns the sorted tags of those of the cards that are tagged,
+// keyed by card ID.
+func GetCardTags(cardIDs []int) (map[int][]string, error) {
+	tags := map[int][]string{}
+	if len(cardIDs) == 0 {
+		return tags, nil
+	}
+	placeholders, args := idList(cardIDs)
+	rows, err := DB.Query("SELECT card_id, tag FROM card_tag WHERE card_id IN ("+placeholders+") ORDER BY card_id, tag", args...)
+	if err != nil {
+		log.Printf("Error retrieving card tags: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cardID int
+		var tag string
+		if err := rows.Scan(&cardID, &tag); err != nil {
+			log.Printf("Error scanning tag row: %v", err)
+			return nil, err
+		}
+		tags[cardID] = append(tags[cardID], tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetCardTagsByDeck returns the tags of every tagged card in a deck, keyed by
+// card ID.
+func GetCardTagsByDeck(deckID int) (map[int][]string, error) {
+	return loadCardTags(DB, deckID)
+}
+
+// GetExportDeck loads a deck with its cards and tags, plus the user's
+// scheduling state when withState is set.
+func GetExportDeck(deckID, userID int, withState bool) (*types.ExportDeck, error) {
+	var deck types.ExportDeck
+	err := DB.QueryRow("SELECT id, name, created_at FROM deck WHERE id = ?", deckID).Scan(&deck.ID, &deck.Name, &deck.CreatedAt)
+	if err != nil {
+		log.Printf("Error retrieving deck %d: %v", deckID, err)
+		return nil, err
+	}
+
+	cards, err := GetCardsByDeck(deckID)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := GetCardTagsByDeck(deckID)
+	if err != nil {
+		return nil, err
+	}
+	states := map[int]types.CardState{}
+	if withState {
+		if states, err = GetCardStatesByDeck(userID, deckID); err != nil {
+			return nil, err
+		}
+	}
+
+	deck.Cards = make([]types.ExportCard, len(cards))
+	for i, card := range cards {
+		deck.Cards[i] = types.ExportCard{
+			ID:        card.ID,
+			Question:  card.Question,
+			Answer:    card.Answer,
+			Tags:      tags[card.ID],
+			CreatedAt: card.CreatedAt,
+		}
+		if deck.Cards[i].Tags == nil {
+			deck.Cards[i].Tags = []string{}
+		}
+		if state, ok := states[card.ID]; ok {
+			deck.Cards[i].State = &state
+		}
+	}
+	return &deck, nil
+}
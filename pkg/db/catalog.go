@@ -0,0 +1,214 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+
+	"go-flashcards-server/pkg/types"
+)
+
+const catalogColumns = `e.id, e.deck_id, e.user_id, e.title, e.description, e.language, e.clone_count,
+	e.published_at, e.updated_at, (SELECT COUNT(*) FROM card c WHERE c.deck_id = e.deck_id AND c.deleted_at IS NULL)`
+
+func scanCatalogEntry(scanner interface{ Scan(...any) error }) (types.CatalogEntry, error) {
+	var entry types.CatalogEntry
+	err := scanner.Scan(&entry.ID, &entry.DeckID, &entry.UserID, &entry.Title, &entry.Description, &entry.Language,
+		&entry.CloneCount, &entry.PublishedAt, &entry.UpdatedAt, &entry.CardCount)
+	entry.Tags = []string{}
+	return entry, err
+}
+
+// PublishDeck creates or replaces the catalog listing of a deck and returns
+// the listing ID.
+func PublishDeck(entry types.CatalogEntry) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	insert := `INSERT INTO catalog_entry (deck_id, user_id, title, description, language, updated_at)
+		VALUES (?, ?, ?, ?, ?, ` + sqlDialect.now + `)`
+	_, err = tx.Exec(sqlDialect.upsert(insert, []string{"deck_id"}, "title", "description", "language", "updated_at"),
+		entry.DeckID, entry.UserID, entry.Title, entry.Description, entry.Language)
+	if err != nil {
+		log.Printf("Error publishing deck %d: %v", entry.DeckID, err)
+		return 0, err
+	}
+
+	var entryID int64
+	if err := tx.QueryRow("SELECT id FROM catalog_entry WHERE deck_id = ?", entry.DeckID).Scan(&entryID); err != nil {
+		log.Printf("Error retrieving catalog entry for deck %d: %v", entry.DeckID, err)
+		return 0, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM catalog_tag WHERE entry_id = ?", entryID); err != nil {
+		log.Printf("Error clearing tags of catalog entry %d: %v", entryID, err)
+		return 0, err
+	}
+	for _, tag := range entry.Tags {
+		if _, err := tx.Exec(sqlDialect.ignore("INSERT INTO catalog_tag (entry_id, tag) VALUES (?, ?)"), entryID, tag); err != nil {
+			log.Printf("Error tagging catalog entry %d: %v", entryID, err)
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing catalog entry: %v", err)
+		return 0, err
+	}
+	return entryID, nil
+}
+
+func UnpublishDeck(deckID int) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM catalog_tag WHERE entry_id IN (SELECT id FROM catalog_entry WHERE deck_id = ?)", deckID); err != nil {
+		log.Printf("Error removing tags of deck %d: %v", deckID, err)
+		return 0, err
+	}
+	result, err := tx.Exec("DELETE FROM catalog_entry WHERE deck_id = ?", deckID)
+	if err != nil {
+		log.Printf("Error unpublishing deck %d: %v", deckID, err)
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error retrieving rows affected: %v", err)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing unpublish: %v", err)
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+func SearchCatalog(filter types.CatalogFilter) ([]types.CatalogEntry, error) {
+	// Listings of trashed decks stay hidden until the deck is restored.
+	conditions := []string{"e.deck_id IN (SELECT id FROM deck WHERE deleted_at IS NULL)"}
+	var args []any
+	if filter.Query != "" {
+		conditions = append(conditions, "(e.title "+sqlDialect.like+" OR e.description "+sqlDialect.like+")")
+		pattern := likePattern(filter.Query)
+		args = append(args, pattern, pattern)
+	}
+	if filter.Language != "" {
+		conditions = append(conditions, "e.language = ?")
+		args = append(args, filter.Language)
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM catalog_tag t WHERE t.entry_id = e.id AND t.tag = ?)")
+		args = append(args, filter.Tag)
+	}
+
+	query := "SELECT " + catalogColumns + " FROM catalog_entry e WHERE " + strings.Join(conditions, " AND ")
+	query += " ORDER BY e.published_at DESC, e.id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error searching catalog: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []types.CatalogEntry{}
+	index := map[int]int{}
+	for rows.Next() {
+		entry, err := scanCatalogEntry(rows)
+		if err != nil {
+			log.Printf("Error scanning catalog row: %v", err)
+			return nil, err
+		}
+		index[entry.ID] = len(entries)
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(entries)), ",")
+	ids := make([]any, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	tagRows, err := DB.Query("SELECT entry_id, tag FROM catalog_tag WHERE entry_id IN ("+placeholders+") ORDER BY tag", ids...)
+	if err != nil {
+		log.Printf("Error retrieving catalog tags: %v", err)
+		return nil, err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var entryID int
+		var tag string
+		if err := tagRows.Scan(&entryID, &tag); err != nil {
+			log.Printf("Error scanning catalog tag: %v", err)
+			return nil, err
+		}
+		entries[index[entryID]].Tags = append(entries[index[entryID]].Tags, tag)
+	}
+	return entries, nil
+}
+
+// GetCatalogEntry returns a catalog listing with its tags, or nil when it does
+// not exist.
+func GetCatalogEntry(entryID int) (*types.CatalogEntry, error) {
+	entry, err := scanCatalogEntry(DB.QueryRow("SELECT "+catalogColumns+` FROM catalog_entry e
+		WHERE e.id = ? AND e.deck_id IN (SELECT id FROM deck WHERE deleted_at IS NULL)`, entryID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving catalog entry %d: %v", entryID, err)
+		return nil, err
+	}
+
+	rows, err := DB.Query("SELECT tag FROM catalog_tag WHERE entry_id = ? ORDER BY tag", entryID)
+	if err != nil {
+		log.Printf("Error retrieving tags of catalog entry %d: %v", entryID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			log.Printf("Error scanning catalog tag: %v", err)
+			return nil, err
+		}
+		entry.Tags = append(entry.Tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func IncrementCloneCount(entryID int) error {
+	if _, err := DB.Exec("UPDATE catalog_entry SET clone_count = clone_count + 1, updated_at = updated_at WHERE id = ?", entryID); err != nil {
+		log.Printf("Error counting clone of catalog entry %d: %v", entryID, err)
+		return err
+	}
+	return nil
+}
+
+func IsDeckPublished(deckID int) (bool, error) {
+	var count int
+	if err := DB.QueryRow(`SELECT COUNT(*) FROM catalog_entry
+		WHERE deck_id = ? AND deck_id IN (SELECT id FROM deck WHERE deleted_at IS NULL)`, deckID).Scan(&count); err != nil {
+		log.Printf("Error checking whether deck %d is published: %v", deckID, err)
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,245 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// CreateClassroom creates a classroom and enrolls its owner as teacher.
+func CreateClassroom(ownerID int, name, joinCode string) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	classroomID, err := insertID(tx, "INSERT INTO classroom (owner_id, name, join_code) VALUES (?, ?, ?)", ownerID, name, joinCode)
+	if err != nil {
+		log.Printf("Error creating classroom: %v", err)
+		return 0, err
+	}
+	_, err = tx.Exec("INSERT INTO classroom_member (classroom_id, user_id, role) VALUES (?, ?, ?)",
+		classroomID, ownerID, types.ClassRoleTeacher)
+	if err != nil {
+		log.Printf("Error enrolling teacher in classroom %d: %v", classroomID, err)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing classroom: %v", err)
+		return 0, err
+	}
+	return classroomID, nil
+}
+
+func GetClassroomsByUser(userID int) ([]types.Classroom, error) {
+	query := `SELECT c.id, c.owner_id, c.name, c.join_code, m.role, c.created_at
+		FROM classroom c JOIN classroom_member m ON m.classroom_id = c.id
+		WHERE m.user_id = ?`
+	rows, err := DB.Query(query, userID)
+	if err != nil {
+		log.Printf("Error retrieving classrooms: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	classrooms := []types.Classroom{}
+	for rows.Next() {
+		var classroom types.Classroom
+		if err := rows.Scan(&classroom.ID, &classroom.OwnerID, &classroom.Name, &classroom.JoinCode, &classroom.Role, &classroom.CreatedAt); err != nil {
+			log.Printf("Error scanning classroom row: %v", err)
+			return nil, err
+		}
+		if classroom.Role != types.ClassRoleTeacher {
+			classroom.JoinCode = ""
+		}
+		classrooms = append(classrooms, classroom)
+	}
+	return classrooms, rows.Err()
+}
+
+// GetClassroomRole returns the user's role in a classroom, or an empty string
+// when they are not a member.
+func GetClassroomRole(classroomID, userID int) (string, error) {
+	var role string
+	err := DB.QueryRow("SELECT role FROM classroom_member WHERE classroom_id = ? AND user_id = ?", classroomID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving membership of classroom %d: %v", classroomID, err)
+		return "", err
+	}
+	return role, nil
+}
+
+// JoinClassroom enrolls a user as student of the classroom with the given join
+// code. It returns nil when the code does not match any classroom.
+func JoinClassroom(userID int, joinCode string) (*types.Classroom, error) {
+	var classroom types.Classroom
+	err := DB.QueryRow("SELECT id, owner_id, name, created_at FROM classroom WHERE join_code = ?", joinCode).
+		Scan(&classroom.ID, &classroom.OwnerID, &classroom.Name, &classroom.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving classroom by code: %v", err)
+		return nil, err
+	}
+
+	_, err = DB.Exec(sqlDialect.ignore("INSERT INTO classroom_member (classroom_id, user_id, role) VALUES (?, ?, ?)"),
+		classroom.ID, userID, types.ClassRoleStudent)
+	if err != nil {
+		log.Printf("Error joining classroom %d: %v", classroom.ID, err)
+		return nil, err
+	}
+	role, err := GetClassroomRole(classroom.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	classroom.Role = role
+	return &classroom, nil
+}
+
+func RemoveClassroomMember(classroomID, userID int) (int64, error) {
+	result, err := DB.Exec("DELETE FROM classroom_member WHERE classroom_id = ? AND user_id = ? AND role = ?",
+		classroomID, userID, types.ClassRoleStudent)
+	if err != nil {
+		log.Printf("Error removing user %d from classroom %d: %v", userID, classroomID, err)
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error retrieving rows affected: %v", err)
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+func AssignDeck(classroomID, deckID int, dueAt *time.Time) error {
+	var due *string
+	if dueAt != nil {
+		formatted := sqlTime(*dueAt)
+		due = &formatted
+	}
+	insert := "INSERT INTO classroom_deck (classroom_id, deck_id, due_at) VALUES (?, ?, ?)"
+	_, err := DB.Exec(sqlDialect.upsert(insert, []string{"classroom_id", "deck_id"}, "due_at"), classroomID, deckID, due)
+	if err != nil {
+		log.Printf("Error assigning deck %d to classroom %d: %v", deckID, classroomID, err)
+		return err
+	}
+	return nil
+}
+
+func UnassignDeck(classroomID, deckID int) (int64, error) {
+	result, err := DB.Exec("DELETE FROM classroom_deck WHERE classroom_id = ? AND deck_id = ?", classroomID, deckID)
+	if err != nil {
+		log.Printf("Error unassigning deck %d from classroom %d: %v", deckID, classroomID, err)
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error retrieving rows affected: %v", err)
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+func GetAssignments(classroomID int) ([]types.Assignment, error) {
+	query := `SELECT cd.classroom_id, cd.deck_id, d.name, cd.due_at, cd.assigned_at
+		FROM classroom_deck cd JOIN deck d ON d.id = cd.deck_id
+		WHERE cd.classroom_id = ? AND d.deleted_at IS NULL
+		ORDER BY cd.due_at IS NULL, cd.due_at, cd.assigned_at`
+	rows, err := DB.Query(query, classroomID)
+	if err != nil {
+		log.Printf("Error retrieving assignments of classroom %d: %v", classroomID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []types.Assignment{}
+	for rows.Next() {
+		var assignment types.Assignment
+		var dueAt sql.NullString
+		if err := rows.Scan(&assignment.ClassroomID, &assignment.DeckID, &assignment.DeckName, &dueAt, &assignment.AssignedAt); err != nil {
+			log.Printf("Error scanning assignment row: %v", err)
+			return nil, err
+		}
+		if dueAt.Valid {
+			assignment.DueAt = &dueAt.String
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, rows.Err()
+}
+
+// IsDeckAssignedToUser reports whether the deck is assigned to any classroom
+// the user belongs to.
+func IsDeckAssignedToUser(deckID, userID int) (bool, error) {
+	var count int
+	err := DB.QueryRow(`SELECT COUNT(*) FROM classroom_deck cd
+		JOIN classroom_member m ON m.classroom_id = cd.classroom_id
+		WHERE cd.deck_id = ? AND m.user_id = ?`, deckID, userID).Scan(&count)
+	if err != nil {
+		log.Printf("Error checking assignment of deck %d: %v", deckID, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetClassroomProgress reports, for every student and assigned deck, how much
+// of the deck the student has studied and how well they retain it.
+func GetClassroomProgress(classroomID int) ([]types.StudentProgress, error) {
+	query := `SELECT u.id, u.first_name, u.last_name, u.email, cd.deck_id, cd.due_at,
+			cd.due_at IS NOT NULL AND cd.due_at < ` + sqlDialect.now + `,
+			(SELECT COUNT(*) FROM card c WHERE c.deck_id = cd.deck_id AND c.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM card_state cs JOIN card c ON c.id = cs.card_id
+				WHERE c.deck_id = cd.deck_id AND c.deleted_at IS NULL AND cs.user_id = u.id),
+			(SELECT COUNT(*) FROM card_state cs JOIN card c ON c.id = cs.card_id
+				WHERE c.deck_id = cd.deck_id AND c.deleted_at IS NULL AND cs.user_id = u.id AND cs.due_at < ` + sqlDialect.now + `),
+			(SELECT COUNT(*) FROM review_log rl JOIN card c ON c.id = rl.card_id
+				WHERE c.deck_id = cd.deck_id AND rl.user_id = u.id),
+			(SELECT COUNT(*) FROM review_log rl JOIN card c ON c.id = rl.card_id
+				WHERE c.deck_id = cd.deck_id AND rl.user_id = u.id AND rl.grade >= 3)
+		FROM classroom_member m
+		JOIN user u ON u.id = m.user_id
+		JOIN classroom_deck cd ON cd.classroom_id = m.classroom_id
+		JOIN deck d ON d.id = cd.deck_id
+		WHERE m.classroom_id = ? AND m.role = ? AND d.deleted_at IS NULL
+		ORDER BY u.last_name, u.first_name, cd.deck_id`
+	rows, err := DB.Query(query, classroomID, types.ClassRoleStudent)
+	if err != nil {
+		log.Printf("Error retrieving progress of classroom %d: %v", classroomID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := []types.StudentProgress{}
+	for rows.Next() {
+		var p types.StudentProgress
+		var dueAt sql.NullString
+		var pastDue bool
+		var passed int
+		err := rows.Scan(&p.UserID, &p.FirstName, &p.LastName, &p.Email, &p.DeckID, &dueAt, &pastDue,
+			&p.CardsTotal, &p.CardsStudied, &p.CardsOverdue, &p.Reviews, &passed)
+		if err != nil {
+			log.Printf("Error scanning progress row: %v", err)
+			return nil, err
+		}
+		if dueAt.Valid {
+			p.DueAt = &dueAt.String
+		}
+		if p.Reviews > 0 {
+			p.Retention = float64(passed) / float64(p.Reviews)
+		}
+		p.Late = pastDue && p.CardsStudied < p.CardsTotal
+		progress = append(progress, p)
+	}
+	return progress, rows.Err()
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Database is the connection opened by Init. Queries are written with ?
+// placeholders; it rewrites them for the dialect before they reach the
+// driver.
+type Database struct {
+	*sql.DB
+}
+
+func (d *Database) Exec(query string, args ...any) (sql.Result, error) {
+	return d.DB.Exec(sqlDialect.rewrite(query), args...)
+}
+
+func (d *Database) Query(query string, args ...any) (*sql.Rows, error) {
+	return d.DB.Query(sqlDialect.rewrite(query), args...)
+}
+
+func (d *Database) QueryRow(query string, args ...any) *sql.Row {
+	return d.DB.QueryRow(sqlDialect.rewrite(query), args...)
+}
+
+func (d *Database) Prepare(query string) (*sql.Stmt, error) {
+	return d.DB.Prepare(sqlDialect.rewrite(query))
+}
+
+func (d *Database) Begin() (*Tx, error) {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx}, nil
+}
+
+// Tx is a transaction on the Database, rewriting queries the same way.
+type Tx struct {
+	*sql.Tx
+}
+
+func (t *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return t.Tx.Exec(sqlDialect.rewrite(query), args...)
+}
+
+func (t *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.Query(sqlDialect.rewrite(query), args...)
+}
+
+func (t *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return t.Tx.QueryRow(sqlDialect.rewrite(query), args...)
+}
+
+func (t *Tx) Prepare(query string) (*sql.Stmt, error) {
+	return t.Tx.Prepare(sqlDialect.rewrite(query))
+}
+
+// insertID runs an INSERT and returns the id of the new row.
+func insertID(q querier, insert string, args ...any) (int64, error) {
+	if sqlDialect.returning {
+		var id int64
+		err := q.QueryRow(insert+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := q.Exec(insert, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// userTable matches references to the user table, whose name is reserved in
+// PostgreSQL.
+var userTable = regexp.MustCompile(`\b(FROM|JOIN|INTO|UPDATE|EXISTS) user\b`)
+
+// rewritePostgres numbers the ? placeholders outside string literals and
+// quotes the user table.
+func rewritePostgres(query string) string {
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return userTable.ReplaceAllString(b.String(), `$1 "user"`)
+}
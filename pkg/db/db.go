@@ -4,18 +4,42 @@ import (
 	"database/sql"
 	"fmt"
 	"go-flashcards-server/pkg/config"
-	"go-flashcards-server/pkg/types"
 	"log"
+	"net/url"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB
+var DB *Database
 
 func Init() {
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", config.DBUsername, config.DBPassword, config.DBHost, config.DBPort, config.DBName)
+	var conn *sql.DB
 	var err error
-	DB, err = sql.Open("mysql", dataSourceName)
+	switch config.DBDriver {
+	case "sqlite":
+		// Transactions take the write lock up front and wait for each other
+		// instead of failing when two try to upgrade a read lock at once.
+		conn, err = sql.Open("sqlite", "file:"+config.DBPath+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate")
+		sqlDialect = sqliteDialect
+	case "postgres":
+		dataSourceName := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(config.DBUsername, config.DBPassword),
+			Host:     config.DBHost + ":" + config.DBPort,
+			Path:     config.DBName,
+			RawQuery: url.Values{"sslmode": {config.DBSSLMode}}.Encode(),
+		}
+		conn, err = sql.Open("postgres", dataSourceName.String())
+		sqlDialect = postgresDialect
+	default:
+		// DEFAULT CURRENT_TIMESTAMP follows the session time zone, so pin it
+		// to UTC like the times the queries write.
+		dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?time_zone=%s", config.DBUsername, config.DBPassword, config.DBHost, config.DBPort, config.DBName, url.QueryEscape("'+00:00'"))
+		conn, err = sql.Open("mysql", dataSourceName)
+	}
+	DB = &Database{conn}
 	if err != nil {
 		log.Fatalf("Error connecting to database: %v", err)
 	}
@@ -23,42 +47,31 @@ func Init() {
 	if err != nil {
 		log.Fatalf("Error pinging database: %v", err)
 	}
+	migrate()
 	fmt.Println("Database connected")
 }
 
 func CreateDeck(userID int, name string) (int64, error) {
-	query := "INSERT INTO deck (user_id, name) VALUES (?, ?)"
-	result, err := DB.Exec(query, userID, name)
-	if err != nil {
-		log.Printf("Error creating deck: %v", err)
-		return 0, err
-	}
-	return result.LastInsertId()
+	return insertDeck(DB, userID, name)
 }
 
-func GetDecksByUser(userID int) ([]types.Deck, error) {
-	query := "SELECT id, user_id, name, created_at FROM deck WHERE user_id = ?"
-	rows, err := DB.Query(query, userID)
+// insertDeck creates a deck at the end of the user's decks.
+func insertDeck(q querier, userID int, name string) (int64, error) {
+	position, err := deckList(userID).next(q)
 	if err != nil {
-		log.Printf("Error retrieving decks: %v", err)
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-
-	var decks []types.Deck
-	for rows.Next() {
-		var deck types.Deck
-		if err := rows.Scan(&deck.ID, &deck.UserID, &deck.Name, &deck.CreatedAt); err != nil {
-			log.Printf("Error scanning deck row: %v", err)
-			return nil, err
-		}
-		decks = append(decks, deck)
+	query := "INSERT INTO deck (user_id, name, position) VALUES (?, ?, ?)"
+	deckID, err := insertID(q, query, userID, name, position)
+	if err != nil {
+		log.Printf("Error creating deck: %v", err)
+		return 0, err
 	}
-	return decks, nil
+	return deckID, nil
 }
 
 func UpdateDeck(deckID int, name string) (int64, error) {
-	query := "UPDATE deck SET name = ? WHERE id = ?"
+	query := "UPDATE deck SET name = ?, updated_at = " + sqlDialect.now + " WHERE id = ? AND deleted_at IS NULL"
 	result, err := DB.Exec(query, name, deckID)
 	if err != nil {
 		log.Printf("Error update deck with ID %d: %v\n", deckID, err)
@@ -73,41 +86,135 @@ func UpdateDeck(deckID int, name string) (int64, error) {
 	return rowsAffected, nil
 }
 
-func DeleteDeck(deckID int) (int64, error) {
-	query := "DELETE FROM deck WHERE id = ?"
-	result, err := DB.Exec(query, deckID)
+// DeleteDeck moves a deck to the trash. Its cards stay with it and come back
+// when the deck is restored, unless targetDeckID is set: then its cards move
+// to that deck first, keeping their review state, history and tags. Shares,
+// catalog listings and classroom assignments stay hidden while the deck is in
+// the trash and are removed when it is purged. It returns the number of cards
+// moved.
+func DeleteDeck(deckID, targetDeckID int) (int64, int, error) {
+	tx, err := DB.Begin()
 	if err != nil {
-		log.Printf("Error deleting deck with id %d: %v\n", deckID, err)
-		return 0, err
+		log.Printf("Error starting transaction: %v", err)
+		return 0, 0, err
 	}
+	defer tx.Rollback()
 
+	query := "UPDATE deck SET deleted_at = " + sqlDialect.now + " WHERE id = ? AND deleted_at IS NULL"
+	result, err := tx.Exec(query, deckID)
+	if err != nil {
+		log.Printf("Error deleting deck with id %d: %v\n", deckID, err)
+		return 0, 0, err
+	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		log.Printf("Error retrieving rows: %v", err)
-		return 0, err
+		return 0, 0, err
 	}
-	return rowsAffected, nil
+	if rowsAffected == 0 {
+		return 0, 0, nil
+	}
+
+	moved := 0
+	if targetDeckID != 0 {
+		cards, err := loadCards(tx, deckID)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, cardID := range sortedCardIDs(cards) {
+			if err := moveCard(tx, cardID, targetDeckID); err != nil {
+				return 0, 0, err
+			}
+			moved++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing deck deletion: %v", err)
+		return 0, 0, err
+	}
+	return rowsAffected, moved, nil
 }
 
-func UpdateCard(cardID int, question, answer string) (int64, error) {
-	query := "UPDATE card SET question = ?, answer = ? WHERE id = ?"
-	result, err := DB.Exec(query, question, answer, cardID)
+// deleteDeck removes a deck and all of its cards permanently, along with its
+// shares, catalog listing, classroom assignments and fork links. Forks of the
+// deck become standalone decks.
+func deleteDeck(q querier, deckID int) error {
+	rows, err := q.Query("SELECT id FROM card WHERE deck_id = ?", deckID)
 	if err != nil {
-		log.Printf("Error update card with ID %d: %v\n", cardID, err)
-		return 0, err
+		log.Printf("Error retrieving cards of deck %d: %v", deckID, err)
+		return err
+	}
+	cardIDs := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Printf("Error scanning card id: %v", err)
+			return err
+		}
+		cardIDs = append(cardIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, cardID := range cardIDs {
+		if _, err := deleteCard(q, cardID); err != nil {
+			return err
+		}
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	statements := []string{
+		"DELETE FROM deck_share WHERE deck_id = ?",
+		"DELETE FROM catalog_tag WHERE entry_id IN (SELECT id FROM catalog_entry WHERE deck_id = ?)",
+		"DELETE FROM catalog_entry WHERE deck_id = ?",
+		"DELETE FROM classroom_deck WHERE deck_id = ?",
+		"DELETE FROM card_fork WHERE deck_id = ?",
+		"DELETE FROM card_fork WHERE deck_id IN (SELECT deck_id FROM deck_fork WHERE source_deck_id = ?)",
+		"DELETE FROM deck_fork WHERE deck_id = ?",
+		"DELETE FROM deck_fork WHERE source_deck_id = ?",
+		"DELETE FROM deck WHERE id = ?",
+	}
+	for _, statement := range statements {
+		if _, err := q.Exec(statement, deckID); err != nil {
+			log.Printf("Error deleting deck %d: %v", deckID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateCard edits a card on behalf of userID, recording a revision. It
+// reports false when the card does not exist.
+func UpdateCard(cardID, userID int, question, answer string) (bool, error) {
+	tx, err := DB.Begin()
 	if err != nil {
-		log.Printf("Error retrieving rows affected: %v", err)
-		return 0, err
+		log.Printf("Error starting transaction: %v", err)
+		return false, err
 	}
-	return rowsAffected, nil
+	defer tx.Rollback()
+
+	found, err := updateCard(tx, cardID, userID, question, answer)
+	if err != nil || !found {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing card update: %v", err)
+		return false, err
+	}
+	return true, nil
 }
 
+// DeleteCard moves a card to the trash.
 func DeleteCard(cardID int) (int64, error) {
+	return trashCard(DB, cardID)
+}
+
+// deleteCard removes a card permanently.
+func deleteCard(q querier, cardID int) (int64, error) {
 	query := "DELETE FROM card WHERE id = ?"
-	result, err := DB.Exec(query, cardID)
+	result, err := q.Exec(query, cardID)
 	if err != nil {
 		log.Printf("Error deleting card with id %d: %v\n", cardID, err)
 		return 0, err
@@ -118,5 +225,30 @@ func DeleteCard(cardID int) (int64, error) {
 		log.Printf("Error retrieving rows: %v", err)
 		return 0, err
 	}
+
+	// Keep the upstream link so a forked card the user deleted is not offered
+	// again as an upstream addition.
+	if _, err := q.Exec("UPDATE card_fork SET card_id = NULL WHERE card_id = ?", cardID); err != nil {
+		log.Printf("Error unlinking card %d: %v", cardID, err)
+		return 0, err
+	}
+	// Review state, history, tags and revisions belong to the card and go
+	// with it.
+	for _, table := range []string{"card_state", "review_log", "card_tag", "card_revision"} {
+		if _, err := q.Exec("DELETE FROM "+table+" WHERE card_id = ?", cardID); err != nil {
+			log.Printf("Error deleting %s rows of card %d: %v", table, cardID, err)
+			return 0, err
+		}
+	}
 	return rowsAffected, nil
 }
+
+func GetDeckName(deckID int) (string, error) {
+	var name string
+	err := DB.QueryRow("SELECT name FROM deck WHERE id = ?", deckID).Scan(&name)
+	if err != nil {
+		log.Printf("Error retrieving deck %d: %v", deckID, err)
+		return "", err
+	}
+	return name, nil
+}
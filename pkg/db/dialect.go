@@ -0,0 +1,160 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dialect holds the SQL that differs between the supported databases. Queries
+// are otherwise written to run unchanged on all of them; times are stored and
+// compared as "2006-01-02 15:04:05" in UTC everywhere.
+type dialect struct {
+	// now is the current UTC time.
+	now string
+	// tomorrow is the start of the next UTC day.
+	tomorrow string
+	// daysAgo returns the time n days before now, where n is an SQL
+	// expression such as "?".
+	daysAgo func(n string) string
+	// ignore turns an INSERT into one that skips rows whose key exists.
+	ignore func(insert string) string
+	// upsert turns an INSERT into one that overwrites columns of the row
+	// with the same keys.
+	upsert func(insert string, keys []string, columns ...string) string
+	// columnExists counts the columns named by its table and column
+	// arguments.
+	columnExists string
+	// table adapts a CREATE TABLE statement from the schema, returning it with
+	// any statements it needs after it.
+	table func(statement string) []string
+	// column adapts a column definition from the schema.
+	column func(definition string) string
+	// like matches a column case-insensitively against a pattern placeholder,
+	// with \ escaping the wildcards in it.
+	like string
+	// date returns the day, as "2006-01-02", of a time expression.
+	date func(expr string) string
+	// returning is set when inserted ids come from RETURNING rather than
+	// LastInsertId.
+	returning bool
+	// rewrite adapts a query before it is sent to the database.
+	rewrite func(query string) string
+}
+
+var mysqlDialect = dialect{
+	now:      "UTC_TIMESTAMP()",
+	tomorrow: "(UTC_DATE() + INTERVAL 1 DAY)",
+	daysAgo:  func(n string) string { return "(UTC_TIMESTAMP() - INTERVAL " + n + " DAY)" },
+	ignore:   func(insert string) string { return strings.Replace(insert, "INSERT", "INSERT IGNORE", 1) },
+	upsert: func(insert string, keys []string, columns ...string) string {
+		updates := make([]string, len(columns))
+		for i, column := range columns {
+			updates[i] = column + " = VALUES(" + column + ")"
+		}
+		return insert + " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	},
+	columnExists: `SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`,
+	table:   func(statement string) []string { return []string{statement} },
+	column:  func(definition string) string { return definition },
+	like:    "LIKE ?", // \ is MySQL's escape character already
+	date:    func(expr string) string { return "DATE(" + expr + ")" },
+	rewrite: func(query string) string { return query },
+}
+
+var (
+	tableName   = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+)`)
+	inlineIndex = regexp.MustCompile(`,\s*INDEX (\w+) \(([^)]*)\)`)
+	datetime    = regexp.MustCompile(`\bDATETIME\b`)
+	doubleType  = regexp.MustCompile(`\bDOUBLE\b`)
+)
+
+var sqliteDialect = dialect{
+	now:          "datetime('now')",
+	tomorrow:     "date('now', '+1 day')",
+	daysAgo:      func(n string) string { return "datetime('now', '-' || " + n + " || ' days')" },
+	ignore:       onConflictDoNothing,
+	upsert:       onConflictUpdate,
+	columnExists: "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?",
+	table: func(statement string) []string {
+		statement = strings.ReplaceAll(statement, "INT AUTO_INCREMENT PRIMARY KEY", "INTEGER PRIMARY KEY AUTOINCREMENT")
+		return splitIndexes(sqliteColumn(statement))
+	},
+	column:  sqliteColumn,
+	like:    `LIKE ? ESCAPE '\'`,
+	date:    func(expr string) string { return "DATE(" + expr + ")" },
+	rewrite: func(query string) string { return query },
+}
+
+// sqliteColumn declares times as TEXT: the driver would otherwise return
+// DATETIME columns as time.Time rather than in the stored format.
+func sqliteColumn(definition string) string {
+	return datetime.ReplaceAllString(definition, "TEXT")
+}
+
+// postgresNow is the current UTC time in the stored format.
+const postgresNow = "to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')"
+
+var postgresDialect = dialect{
+	now:      postgresNow,
+	tomorrow: "to_char((now() AT TIME ZONE 'UTC')::date + 1, 'YYYY-MM-DD')",
+	daysAgo: func(n string) string {
+		return "to_char(now() AT TIME ZONE 'UTC' - make_interval(days => " + n + "), 'YYYY-MM-DD HH24:MI:SS')"
+	},
+	ignore: onConflictDoNothing,
+	upsert: onConflictUpdate,
+	columnExists: `SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`,
+	table: func(statement string) []string {
+		statement = strings.ReplaceAll(statement, "INT AUTO_INCREMENT PRIMARY KEY", "SERIAL PRIMARY KEY")
+		return splitIndexes(postgresColumn(statement))
+	},
+	column:    postgresColumn,
+	like:      `ILIKE ? ESCAPE '\'`,
+	date:      func(expr string) string { return "SUBSTR(" + expr + ", 1, 10)" },
+	returning: true,
+	rewrite:   rewritePostgres,
+}
+
+// postgresColumn stores times as text that sorts byte by byte, as on the
+// other databases.
+func postgresColumn(definition string) string {
+	definition = datetime.ReplaceAllString(definition, `VARCHAR(19) COLLATE "C"`)
+	definition = strings.ReplaceAll(definition, "DEFAULT CURRENT_TIMESTAMP", "DEFAULT "+postgresNow)
+	definition = doubleType.ReplaceAllString(definition, "DOUBLE PRECISION")
+	return strings.ReplaceAll(definition, "LONGBLOB", "BYTEA")
+}
+
+// splitIndexes moves the indexes declared inside a CREATE TABLE statement
+// into statements of their own, for databases without inline indexes.
+func splitIndexes(statement string) []string {
+	table := tableName.FindStringSubmatch(statement)[1]
+	statements := []string{""}
+	for _, index := range inlineIndex.FindAllStringSubmatch(statement, -1) {
+		statements = append(statements, "CREATE INDEX IF NOT EXISTS "+index[1]+" ON "+table+" ("+index[2]+")")
+	}
+	statement = inlineIndex.ReplaceAllString(statement, "")
+	statements[0] = strings.ReplaceAll(statement, " ON UPDATE CURRENT_TIMESTAMP", "")
+	return statements
+}
+
+func onConflictDoNothing(insert string) string {
+	return insert + " ON CONFLICT DO NOTHING"
+}
+
+func onConflictUpdate(insert string, keys []string, columns ...string) string {
+	updates := make([]string, len(columns))
+	for i, column := range columns {
+		updates[i] = column + " = excluded." + column
+	}
+	return insert + " ON CONFLICT (" + strings.Join(keys, ", ") + ") DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+// sqlDialect is the dialect of the database opened by Init.
+var sqlDialect = mysqlDialect
+
+// sqlTime formats a time argument the way times are stored.
+func sqlTime(t time.Time) string {
+	return t.UTC().Format(time.DateTime)
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"log"
+
+	"go-flashcards-server/pkg/dedupe"
+	"go-flashcards-server/pkg/types"
+)
+
+// GetDuplicateIndex indexes the cards new cards for deckID are compared with:
+// the deck itself, or every deck the user owns or shares for the collection
+// scope.
+func GetDuplicateIndex(userID, deckID int, policy types.DuplicatePolicy) (*dedupe.Index, error) {
+	return loadDuplicateIndex(DB, userID, deckID, policy)
+}
+
+func loadDuplicateIndex(q querier, userID, deckID int, policy types.DuplicatePolicy) (*dedupe.Index, error) {
+	query := "SELECT id, deck_id, question, answer FROM card WHERE deck_id = ? AND deleted_at IS NULL ORDER BY id"
+	args := []any{deckID}
+	if policy.Scope == types.DuplicateScopeCollection {
+		query = `SELECT c.id, c.deck_id, c.question, c.answer FROM card c JOIN deck d ON d.id = c.deck_id
+			WHERE c.deleted_at IS NULL AND d.deleted_at IS NULL
+				AND (d.user_id = ? OR d.id IN (SELECT deck_id FROM deck_share WHERE user_id = ?))
+			ORDER BY c.id`
+		args = []any{userID, userID}
+	}
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		log.Printf("Error retrieving cards for duplicate check: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := dedupe.NewIndex(policy.Similarity)
+	for rows.Next() {
+		var card types.DuplicateCard
+		if err := rows.Scan(&card.ID, &card.DeckID, &card.Question, &card.Answer); err != nil {
+			log.Printf("Error scanning card row: %v", err)
+			return nil, err
+		}
+		index.Add(card)
+	}
+	return index, rows.Err()
+}
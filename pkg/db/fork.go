@@ -0,0 +1,319 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"sort"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// querier is satisfied by both *Database and *Tx.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type cardLink struct {
+	sourceCardID int
+	cardID       sql.NullInt64
+	baseQuestion string
+	baseAnswer   string
+}
+
+// CloneDeck copies a deck with its cards and their tags to userID in one
+// transaction, recording per-card provenance so later upstream edits can be
+// merged.
+func CloneDeck(userID, sourceDeckID int, name string) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	sources, err := loadDeckCards(tx, sourceDeckID)
+	if err != nil {
+		return 0, err
+	}
+	tags, err := loadCardTags(tx, sourceDeckID)
+	if err != nil {
+		return 0, err
+	}
+	deckID, err := insertDeck(tx, userID, name)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("INSERT INTO deck_fork (deck_id, source_deck_id) VALUES (?, ?)", deckID, sourceDeckID); err != nil {
+		log.Printf("Error linking deck %d to upstream %d: %v", deckID, sourceDeckID, err)
+		return 0, err
+	}
+	for _, source := range sources {
+		cardID, err := insertCard(tx, int(deckID), source.Question, source.Answer, tags[source.ID])
+		if err != nil {
+			return 0, err
+		}
+		if err := linkCard(tx, int(deckID), source.ID, cardID, source.Question, source.Answer); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing cloned deck: %v", err)
+		return 0, err
+	}
+	return deckID, nil
+}
+
+// linkCard records that cardID is the local copy of an upstream card, as it
+// read when copied.
+func linkCard(q querier, deckID, sourceCardID int, cardID int64, question, answer string) error {
+	_, err := q.Exec("INSERT INTO card_fork (deck_id, source_card_id, card_id, base_question, base_answer) VALUES (?, ?, ?, ?, ?)",
+		deckID, sourceCardID, cardID, question, answer)
+	if err != nil {
+		log.Printf("Error linking card %d to upstream card %d: %v", cardID, sourceCardID, err)
+		return err
+	}
+	return nil
+}
+
+// GetDeckFork returns the upstream link of a deck, or nil when the deck was
+// not cloned from another deck.
+func GetDeckFork(deckID int) (*types.DeckFork, error) {
+	var fork types.DeckFork
+	err := DB.QueryRow("SELECT deck_id, source_deck_id, synced_at FROM deck_fork WHERE deck_id = ?", deckID).
+		Scan(&fork.DeckID, &fork.SourceDeckID, &fork.SyncedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving fork of deck %d: %v", deckID, err)
+		return nil, err
+	}
+	return &fork, nil
+}
+
+// DiffUpstream compares a forked deck against its upstream deck.
+func DiffUpstream(fork types.DeckFork) (*types.UpstreamDiff, error) {
+	return diffUpstream(DB, fork)
+}
+
+func diffUpstream(q querier, fork types.DeckFork) (*types.UpstreamDiff, error) {
+	links, err := loadCardLinks(q, fork.DeckID)
+	if err != nil {
+		return nil, err
+	}
+	local, err := loadCards(q, fork.DeckID)
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := loadCards(q, fork.SourceDeckID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &types.UpstreamDiff{
+		SourceDeckID: fork.SourceDeckID,
+		SyncedAt:     fork.SyncedAt,
+		Added:        []types.CardChange{},
+		Changed:      []types.CardChange{},
+		Removed:      []types.CardChange{},
+	}
+	for _, id := range sortedCardIDs(upstream) {
+		source := upstream[id]
+		link, linked := links[id]
+		if !linked {
+			diff.Added = append(diff.Added, types.CardChange{
+				SourceCardID:     id,
+				UpstreamQuestion: source.Question,
+				UpstreamAnswer:   source.Answer,
+			})
+			continue
+		}
+		if !link.cardID.Valid {
+			// The user deleted their copy; do not offer it again.
+			continue
+		}
+		card, ok := local[int(link.cardID.Int64)]
+		if !ok {
+			continue
+		}
+		if source.Question == link.baseQuestion && source.Answer == link.baseAnswer {
+			continue
+		}
+		locallyEdited := card.Question != link.baseQuestion || card.Answer != link.baseAnswer
+		sameAsUpstream := card.Question == source.Question && card.Answer == source.Answer
+		diff.Changed = append(diff.Changed, types.CardChange{
+			CardID:           card.ID,
+			SourceCardID:     id,
+			LocalQuestion:    card.Question,
+			LocalAnswer:      card.Answer,
+			UpstreamQuestion: source.Question,
+			UpstreamAnswer:   source.Answer,
+			Conflict:         locallyEdited && !sameAsUpstream,
+		})
+	}
+
+	for _, link := range sortedLinks(links) {
+		if _, ok := upstream[link.sourceCardID]; ok || !link.cardID.Valid {
+			continue
+		}
+		card, ok := local[int(link.cardID.Int64)]
+		if !ok {
+			continue
+		}
+		diff.Removed = append(diff.Removed, types.CardChange{
+			CardID:        card.ID,
+			SourceCardID:  link.sourceCardID,
+			LocalQuestion: card.Question,
+			LocalAnswer:   card.Answer,
+			Conflict:      card.Question != link.baseQuestion || card.Answer != link.baseAnswer,
+		})
+	}
+	return diff, nil
+}
+
+// ApplyUpstream merges upstream changes into a forked deck on behalf of
+// userID. Cards are updated in place so each user's scheduling state and
+// review history survive. Conflicting local edits are kept unless
+// takeUpstream is set, and are reported either way.
+func ApplyUpstream(fork types.DeckFork, userID int, takeUpstream bool) (*types.UpstreamMergeResult, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	diff, err := diffUpstream(tx, fork)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := loadCardTags(tx, fork.SourceDeckID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.UpstreamMergeResult{Conflicts: []types.CardChange{}}
+	for _, change := range diff.Added {
+		cardID, err := insertCard(tx, fork.DeckID, change.UpstreamQuestion, change.UpstreamAnswer, tags[change.SourceCardID])
+		if err != nil {
+			return nil, err
+		}
+		if err := linkCard(tx, fork.DeckID, change.SourceCardID, cardID, change.UpstreamQuestion, change.UpstreamAnswer); err != nil {
+			return nil, err
+		}
+		result.Added++
+	}
+
+	for _, change := range diff.Changed {
+		if change.Conflict {
+			result.Conflicts = append(result.Conflicts, change)
+		}
+		if !change.Conflict || takeUpstream {
+			if _, err := updateCard(tx, change.CardID, userID, change.UpstreamQuestion, change.UpstreamAnswer); err != nil {
+				return nil, err
+			}
+			result.Updated++
+		}
+		// Advance the base either way so a kept local edit is not offered again.
+		_, err := tx.Exec("UPDATE card_fork SET base_question = ?, base_answer = ? WHERE deck_id = ? AND source_card_id = ?",
+			change.UpstreamQuestion, change.UpstreamAnswer, fork.DeckID, change.SourceCardID)
+		if err != nil {
+			log.Printf("Error updating base of upstream card %d: %v", change.SourceCardID, err)
+			return nil, err
+		}
+	}
+
+	for _, change := range diff.Removed {
+		if change.Conflict {
+			result.Conflicts = append(result.Conflicts, change)
+		}
+		if !change.Conflict || takeUpstream {
+			if _, err := deleteCard(tx, change.CardID); err != nil {
+				return nil, err
+			}
+			result.Removed++
+		}
+	}
+
+	// Forget links to upstream cards that no longer exist; kept cards become
+	// purely local. Cards in the upstream trash keep their link, so restoring
+	// one is not offered again as a new card.
+	_, err = tx.Exec(`DELETE FROM card_fork WHERE deck_id = ? AND source_card_id NOT IN (
+		SELECT id FROM card WHERE deck_id = ?)`, fork.DeckID, fork.SourceDeckID)
+	if err != nil {
+		log.Printf("Error pruning card links of deck %d: %v", fork.DeckID, err)
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE deck_fork SET synced_at = "+sqlDialect.now+" WHERE deck_id = ?", fork.DeckID); err != nil {
+		log.Printf("Error updating sync time of deck %d: %v", fork.DeckID, err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing upstream merge: %v", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func loadCardLinks(q querier, deckID int) (map[int]cardLink, error) {
+	rows, err := q.Query("SELECT source_card_id, card_id, base_question, base_answer FROM card_fork WHERE deck_id = ?", deckID)
+	if err != nil {
+		log.Printf("Error retrieving card links of deck %d: %v", deckID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := map[int]cardLink{}
+	for rows.Next() {
+		var link cardLink
+		if err := rows.Scan(&link.sourceCardID, &link.cardID, &link.baseQuestion, &link.baseAnswer); err != nil {
+			log.Printf("Error scanning card link row: %v", err)
+			return nil, err
+		}
+		links[link.sourceCardID] = link
+	}
+	return links, rows.Err()
+}
+
+func loadCards(q querier, deckID int) (map[int]types.Card, error) {
+	rows, err := q.Query("SELECT id, deck_id, question, answer, created_at FROM card WHERE deck_id = ? AND deleted_at IS NULL", deckID)
+	if err != nil {
+		log.Printf("Error retrieving cards for deck %d: %v", deckID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := map[int]types.Card{}
+	for rows.Next() {
+		var card types.Card
+		if err := rows.Scan(&card.ID, &card.DeckID, &card.Question, &card.Answer, &card.CreatedAt); err != nil {
+			log.Printf("Error scanning card row: %v", err)
+			return nil, err
+		}
+		cards[card.ID] = card
+	}
+	return cards, rows.Err()
+}
+
+func sortedCardIDs(cards map[int]types.Card) []int {
+	ids := make([]int, 0, len(cards))
+	for id := range cards {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func sortedLinks(links map[int]cardLink) []cardLink {
+	sorted := make([]cardLink, 0, len(links))
+	for _, link := range links {
+		sorted = append(sorted, link)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].sourceCardID < sorted[j].sourceCardID })
+	return sorted
+}
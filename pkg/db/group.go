@@ -0,0 +1,397 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// streakWindowDays bounds how far back study streaks are computed.
+const streakWindowDays = 366
+
+// CreateGroup creates a study group with its owner as first member.
+func CreateGroup(ownerID int, name, inviteCode string) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	groupID, err := insertID(tx, "INSERT INTO study_group (owner_id, name, invite_code) VALUES (?, ?, ?)", ownerID, name, inviteCode)
+	if err != nil {
+		log.Printf("Error creating group: %v", err)
+		return 0, err
+	}
+	if _, err := tx.Exec("INSERT INTO group_member (group_id, user_id) VALUES (?, ?)", groupID, ownerID); err != nil {
+		log.Printf("Error adding owner to group %d: %v", groupID, err)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing group: %v", err)
+		return 0, err
+	}
+	return groupID, nil
+}
+
+func GetGroupsByUser(userID int) ([]types.Group, error) {
+	query := `SELECT g.id, g.owner_id, g.name, g.invite_code, m.hidden, g.created_at
+		FROM study_group g JOIN group_member m ON m.group_id = g.id
+		WHERE m.user_id = ?`
+	rows, err := DB.Query(query, userID)
+	if err != nil {
+		log.Printf("Error retrieving groups: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []types.Group{}
+	for rows.Next() {
+		var group types.Group
+		if err := rows.Scan(&group.ID, &group.OwnerID, &group.Name, &group.InviteCode, &group.Hidden, &group.CreatedAt); err != nil {
+			log.Printf("Error scanning group row: %v", err)
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+func IsGroupMember(groupID, userID int) (bool, error) {
+	var count int
+	err := DB.QueryRow("SELECT COUNT(*) FROM group_member WHERE group_id = ? AND user_id = ?", groupID, userID).Scan(&count)
+	if err != nil {
+		log.Printf("Error checking membership of group %d: %v", groupID, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// JoinGroupByCode adds the user to the group with the given invite code. It
+// returns nil when the code does not match any group.
+func JoinGroupByCode(userID int, inviteCode string) (*types.Group, error) {
+	var group types.Group
+	err := DB.QueryRow("SELECT id, owner_id, name, invite_code, created_at FROM study_group WHERE invite_code = ?", inviteCode).
+		Scan(&group.ID, &group.OwnerID, &group.Name, &group.InviteCode, &group.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving group by code: %v", err)
+		return nil, err
+	}
+
+	if err := addGroupMember(DB, group.ID, userID); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func addGroupMember(q querier, groupID, userID int) error {
+	if _, err := q.Exec(sqlDialect.ignore("INSERT INTO group_member (group_id, user_id) VALUES (?, ?)"), groupID, userID); err != nil {
+		log.Printf("Error adding user %d to group %d: %v", userID, groupID, err)
+		return err
+	}
+	if _, err := q.Exec("DELETE FROM group_invite WHERE group_id = ? AND user_id = ?", groupID, userID); err != nil {
+		log.Printf("Error clearing invite of user %d to group %d: %v", userID, groupID, err)
+		return err
+	}
+	return nil
+}
+
+func InviteToGroup(groupID, userID, invitedBy int) error {
+	_, err := DB.Exec(sqlDialect.ignore("INSERT INTO group_invite (group_id, user_id, invited_by) VALUES (?, ?, ?)"), groupID, userID, invitedBy)
+	if err != nil {
+		log.Printf("Error inviting user %d to group %d: %v", userID, groupID, err)
+		return err
+	}
+	return nil
+}
+
+func GetGroupInvites(userID int) ([]types.GroupInvite, error) {
+	query := `SELECT i.group_id, g.name, i.invited_by, i.created_at
+		FROM group_invite i JOIN study_group g ON g.id = i.group_id
+		WHERE i.user_id = ?`
+	rows, err := DB.Query(query, userID)
+	if err != nil {
+		log.Printf("Error retrieving group invites: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []types.GroupInvite{}
+	for rows.Next() {
+		var invite types.GroupInvite
+		if err := rows.Scan(&invite.GroupID, &invite.GroupName, &invite.InvitedBy, &invite.CreatedAt); err != nil {
+			log.Printf("Error scanning group invite row: %v", err)
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+// AcceptGroupInvite turns a pending invite into a membership. It returns false
+// when there is no such invite.
+func AcceptGroupInvite(groupID, userID int) (bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow("SELECT COUNT(*) FROM group_invite WHERE group_id = ? AND user_id = ?", groupID, userID).Scan(&count)
+	if err != nil {
+		log.Printf("Error retrieving invite to group %d: %v", groupID, err)
+		return false, err
+	}
+	if count == 0 {
+		return false, nil
+	}
+	if err := addGroupMember(tx, groupID, userID); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing group invite: %v", err)
+		return false, err
+	}
+	return true, nil
+}
+
+func DeclineGroupInvite(groupID, userID int) (int64, error) {
+	result, err := DB.Exec("DELETE FROM group_invite WHERE group_id = ? AND user_id = ?", groupID, userID)
+	if err != nil {
+		log.Printf("Error declining invite to group %d: %v", groupID, err)
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error retrieving rows affected: %v", err)
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// SetGroupVisibility sets whether the member is left out of the group's
+// leaderboards and challenge listings.
+func SetGroupVisibility(groupID, userID int, hidden bool) error {
+	_, err := DB.Exec("UPDATE group_member SET hidden = ? WHERE group_id = ? AND user_id = ?", hidden, groupID, userID)
+	if err != nil {
+		log.Printf("Error updating visibility in group %d: %v", groupID, err)
+		return err
+	}
+	return nil
+}
+
+func LeaveGroup(groupID, userID int) (int64, error) {
+	result, err := DB.Exec("DELETE FROM group_member WHERE group_id = ? AND user_id = ?", groupID, userID)
+	if err != nil {
+		log.Printf("Error leaving group %d: %v", groupID, err)
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error retrieving rows affected: %v", err)
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// GetLeaderboard returns review counts, accuracy and current streak for every
+// visible member of a group. Entries are unranked and in no particular order.
+func GetLeaderboard(groupID int, start, end time.Time) ([]types.LeaderboardEntry, error) {
+	query := `SELECT u.id, u.first_name, u.last_name, COUNT(rl.id), COALESCE(SUM(CASE WHEN rl.grade >= 3 THEN 1 ELSE 0 END), 0)
+		FROM group_member m
+		JOIN user u ON u.id = m.user_id
+		LEFT JOIN review_log rl ON rl.user_id = m.user_id AND rl.reviewed_at >= ? AND rl.reviewed_at < ?
+		WHERE m.group_id = ? AND m.hidden = FALSE
+		GROUP BY u.id, u.first_name, u.last_name`
+	rows, err := DB.Query(query, sqlTime(start), sqlTime(end), groupID)
+	if err != nil {
+		log.Printf("Error retrieving leaderboard of group %d: %v", groupID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []types.LeaderboardEntry{}
+	index := map[int]int{}
+	for rows.Next() {
+		var entry types.LeaderboardEntry
+		var passed int
+		if err := rows.Scan(&entry.UserID, &entry.FirstName, &entry.LastName, &entry.Reviews, &passed); err != nil {
+			log.Printf("Error scanning leaderboard row: %v", err)
+			return nil, err
+		}
+		if entry.Reviews > 0 {
+			entry.Accuracy = float64(passed) / float64(entry.Reviews)
+		}
+		index[entry.UserID] = len(entries)
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	asOf := time.Now().UTC()
+	if end.Before(asOf) {
+		asOf = end.Add(-time.Second)
+	}
+	days, err := getStudyDays(groupID, asOf.AddDate(0, 0, -streakWindowDays), asOf)
+	if err != nil {
+		return nil, err
+	}
+	for userID, studied := range days {
+		if i, ok := index[userID]; ok {
+			entries[i].Streak = currentStreak(studied, asOf)
+		}
+	}
+	return entries, nil
+}
+
+// getStudyDays returns, per visible group member, the set of UTC dates on
+// which they reviewed at least one card.
+func getStudyDays(groupID int, since, until time.Time) (map[int]map[string]bool, error) {
+	query := `SELECT DISTINCT rl.user_id, ` + sqlDialect.date("rl.reviewed_at") + `
+		FROM review_log rl JOIN group_member m ON m.user_id = rl.user_id
+		WHERE m.group_id = ? AND m.hidden = FALSE AND rl.reviewed_at >= ? AND rl.reviewed_at <= ?`
+	rows, err := DB.Query(query, groupID, sqlTime(since), sqlTime(until))
+	if err != nil {
+		log.Printf("Error retrieving study days of group %d: %v", groupID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := map[int]map[string]bool{}
+	for rows.Next() {
+		var userID int
+		var day string
+		if err := rows.Scan(&userID, &day); err != nil {
+			log.Printf("Error scanning study day row: %v", err)
+			return nil, err
+		}
+		if days[userID] == nil {
+			days[userID] = map[string]bool{}
+		}
+		days[userID][day] = true
+	}
+	return days, rows.Err()
+}
+
+// currentStreak counts consecutive study days ending on asOf. A streak that
+// ended the day before still counts, since today is not over yet.
+func currentStreak(studied map[string]bool, asOf time.Time) int {
+	day := asOf
+	if !studied[day.Format(time.DateOnly)] {
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for studied[day.Format(time.DateOnly)] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
+func CreateChallenge(challenge types.Challenge, startsAt, endsAt time.Time) (int64, error) {
+	challengeID, err := insertID(DB, `INSERT INTO group_challenge (group_id, created_by, title, metric, target, starts_at, ends_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		challenge.GroupID, challenge.CreatedBy, challenge.Title, challenge.Metric, challenge.Target, sqlTime(startsAt), sqlTime(endsAt))
+	if err != nil {
+		log.Printf("Error creating challenge in group %d: %v", challenge.GroupID, err)
+		return 0, err
+	}
+	return challengeID, nil
+}
+
+func GetChallenges(groupID int) ([]types.Challenge, error) {
+	query := `SELECT id, group_id, created_by, title, metric, target, starts_at, ends_at
+		FROM group_challenge WHERE group_id = ? ORDER BY ends_at DESC`
+	rows, err := DB.Query(query, groupID)
+	if err != nil {
+		log.Printf("Error retrieving challenges of group %d: %v", groupID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	challenges := []types.Challenge{}
+	for rows.Next() {
+		var c types.Challenge
+		if err := rows.Scan(&c.ID, &c.GroupID, &c.CreatedBy, &c.Title, &c.Metric, &c.Target, &c.StartsAt, &c.EndsAt); err != nil {
+			log.Printf("Error scanning challenge row: %v", err)
+			return nil, err
+		}
+		challenges = append(challenges, c)
+	}
+	return challenges, rows.Err()
+}
+
+// GetChallengeProgress returns, for every challenge of a group, each visible
+// member's progress and the group total, which also counts hidden members
+// anonymously. Progress on all challenges is counted in one query.
+func GetChallengeProgress(groupID int) ([]types.ChallengeProgress, error) {
+	challenges, err := GetChallenges(groupID)
+	if err != nil {
+		return nil, err
+	}
+	progress := make([]types.ChallengeProgress, len(challenges))
+	index := map[int]int{}
+	for i, challenge := range challenges {
+		progress[i] = types.ChallengeProgress{Challenge: challenge, Members: []types.ChallengeMemberProgress{}}
+		index[challenge.ID] = i
+	}
+
+	query := `SELECT c.id, u.id, u.first_name, u.last_name, m.hidden,
+			COUNT(rl.id), COALESCE(SUM(CASE WHEN rl.grade >= 3 THEN 1 ELSE 0 END), 0), COUNT(DISTINCT ` + sqlDialect.date("rl.reviewed_at") + `)
+		FROM group_challenge c
+		JOIN group_member m ON m.group_id = c.group_id
+		JOIN user u ON u.id = m.user_id
+		LEFT JOIN review_log rl ON rl.user_id = m.user_id AND rl.reviewed_at >= c.starts_at AND rl.reviewed_at < c.ends_at
+		WHERE c.group_id = ?
+		GROUP BY c.id, u.id, u.first_name, u.last_name, m.hidden
+		ORDER BY u.first_name, u.last_name`
+	rows, err := DB.Query(query, groupID)
+	if err != nil {
+		log.Printf("Error retrieving challenge progress of group %d: %v", groupID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var challengeID int
+		var member types.ChallengeMemberProgress
+		var hidden bool
+		var reviews, correct, days int
+		if err := rows.Scan(&challengeID, &member.UserID, &member.FirstName, &member.LastName, &hidden, &reviews, &correct, &days); err != nil {
+			log.Printf("Error scanning challenge progress row: %v", err)
+			return nil, err
+		}
+		i, ok := index[challengeID]
+		if !ok {
+			// Created after the challenges were listed.
+			continue
+		}
+		p := &progress[i]
+		switch p.Metric {
+		case types.ChallengeMetricCorrect:
+			member.Value = correct
+		case types.ChallengeMetricDays:
+			member.Value = days
+		default:
+			member.Value = reviews
+		}
+		member.Completed = member.Value >= p.Target
+		p.Total += member.Value
+		if !hidden {
+			p.Members = append(p.Members, member)
+		}
+	}
+	return progress, rows.Err()
+}
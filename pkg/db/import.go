@@ -0,0 +1,148 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"go-flashcards-server/pkg/dedupe"
+	"go-flashcards-server/pkg/types"
+)
+
+// ImportDecks inserts imported decks and cards for a user in one transaction.
+// Cards without a deck ID go into the user's deck of the same name when one
+// exists, and cards whose question and answer already exist in the target deck
+// are counted as duplicates instead of inserted. Cards matching the duplicate
+// policy are skipped or reported as warnings, and dedupe.ErrTooManyCards is
+// returned when near-duplicate matching would cover too many cards. progress
+// is told how many cards are done before each one.
+func ImportDecks(userID int, decks []types.ImportDeck, policy types.DuplicatePolicy, progress types.Progress) (*types.ImportReport, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	insertState, err := tx.Prepare(`INSERT INTO card_state (user_id, card_id, due_at, interval_days, ease, reps, lapses)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		log.Printf("Error preparing card state insert: %v", err)
+		return nil, err
+	}
+	defer insertState.Close()
+
+	total, done := 0, 0
+	for _, deck := range decks {
+		total += len(deck.Cards)
+	}
+	report := &types.ImportReport{Decks: []types.ImportedDeck{}, Skipped: []types.ImportIssue{}}
+	checkDuplicates := policy.Mode == types.DuplicatesWarn || policy.Mode == types.DuplicatesReject
+	var collection *dedupe.Index
+	if checkDuplicates && policy.Scope == types.DuplicateScopeCollection {
+		if collection, err = loadDuplicateIndex(tx, userID, 0, policy); err != nil {
+			return nil, err
+		}
+		if err := collection.CheckFuzzy(total); err != nil {
+			return nil, err
+		}
+	}
+	for _, deck := range decks {
+		imported := types.ImportedDeck{DeckID: deck.ID, Name: deck.Name}
+		var err error
+		if deck.ID == 0 {
+			err = tx.QueryRow("SELECT id FROM deck WHERE user_id = ? AND name = ? AND deleted_at IS NULL LIMIT 1", userID, deck.Name).Scan(&imported.DeckID)
+		}
+		if err == sql.ErrNoRows {
+			deckID, err := insertDeck(tx, userID, deck.Name)
+			if err != nil {
+				return nil, err
+			}
+			imported.DeckID = int(deckID)
+			imported.Created = true
+		} else if err != nil {
+			log.Printf("Error retrieving deck %q: %v", deck.Name, err)
+			return nil, err
+		}
+
+		existing, err := loadCards(tx, imported.DeckID)
+		if err != nil {
+			return nil, err
+		}
+		seen := map[string]bool{}
+		for _, card := range existing {
+			seen[card.Question+"\x00"+card.Answer] = true
+		}
+		index := collection
+		if checkDuplicates && index == nil {
+			if index, err = loadDuplicateIndex(tx, userID, imported.DeckID, policy); err != nil {
+				return nil, err
+			}
+			if err := index.CheckFuzzy(len(deck.Cards)); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, card := range deck.Cards {
+			if progress != nil {
+				progress(done, total)
+			}
+			done++
+			key := card.Question + "\x00" + card.Answer
+			if seen[key] {
+				imported.Duplicates++
+				continue
+			}
+			seen[key] = true
+			if index != nil {
+				if matches := index.Find(card.Question); len(matches) > 0 {
+					issue := types.ImportIssue{
+						Item:   fmt.Sprintf("card %q", truncate(card.Question, 60)),
+						Reason: fmt.Sprintf("duplicate of card %d", matches[0].ID),
+					}
+					if policy.Mode == types.DuplicatesReject {
+						imported.Duplicates++
+						report.Skipped = append(report.Skipped, issue)
+						continue
+					}
+					report.Warnings = append(report.Warnings, issue)
+				}
+			}
+
+			cardID, err := insertCard(tx, imported.DeckID, card.Question, card.Answer, card.Tags)
+			if err != nil {
+				return nil, err
+			}
+			if index != nil {
+				index.Add(types.DuplicateCard{ID: int(cardID), DeckID: imported.DeckID, Question: card.Question, Answer: card.Answer})
+			}
+			if card.State != nil {
+				state := card.State
+				_, err = insertState.Exec(userID, cardID, sqlTime(state.DueAt), state.IntervalDays, state.Ease, state.Reps, state.Lapses)
+				if err != nil {
+					log.Printf("Error importing state of card %d: %v", cardID, err)
+					return nil, err
+				}
+			}
+			imported.Imported++
+		}
+
+		report.Imported += imported.Imported
+		report.Duplicates += imported.Duplicates
+		report.Decks = append(report.Decks, imported)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing import: %v", err)
+		return nil, err
+	}
+	return report, nil
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length]) + "..."
+}
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"go-flashcards-server/pkg/types"
+)
+
+const jobColumns = `id, kind, params, status, progress, result, error, artifact_name, created_at, started_at, finished_at`
+
+// CreateJob queues a job, and drops finished jobs older than a week.
+func CreateJob(id string, userID int, kind string, params types.JobParams, input []byte) error {
+	if _, err := DB.Exec("DELETE FROM job WHERE status IN (?, ?) AND finished_at < "+sqlDialect.daysAgo("7"),
+		types.JobDone, types.JobFailed); err != nil {
+		log.Printf("Error removing expired jobs: %v", err)
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	_, err = DB.Exec(`INSERT INTO job (id, user_id, kind, status, params, input, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, `+sqlDialect.now+`)`, id, userID, kind, types.JobQueued, string(encoded), input)
+	if err != nil {
+		log.Printf("Error creating job %s: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+func scanJob(scanner interface{ Scan(...any) error }) (*types.Job, error) {
+	var job types.Job
+	var params string
+	var result, message, artifactName sql.NullString
+	err := scanner.Scan(&job.ID, &job.Kind, &params, &job.Status, &job.Progress, &result, &message, &artifactName,
+		&job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	var decoded types.JobParams
+	if err := json.Unmarshal([]byte(params), &decoded); err == nil {
+		job.Format = decoded.Format
+	}
+	if result.Valid {
+		job.Result = json.RawMessage(result.String)
+	}
+	job.Error = message.String
+	job.ArtifactName = artifactName.String
+	return &job, nil
+}
+
+// GetJob returns a user's job, or nil when it does not exist or belongs to
+// someone else.
+func GetJob(id string, userID int) (*types.Job, error) {
+	row := DB.QueryRow("SELECT "+jobColumns+" FROM job WHERE id = ? AND user_id = ?", id, userID)
+	job, err := scanJob(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving job %s: %v", id, err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetJobsByUser returns a user's jobs, newest first.
+func GetJobsByUser(userID int) ([]types.Job, error) {
+	rows, err := DB.Query("SELECT "+jobColumns+" FROM job WHERE user_id = ? ORDER BY created_at DESC, id", userID)
+	if err != nil {
+		log.Printf("Error retrieving jobs: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []types.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			log.Printf("Error scanning job row: %v", err)
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// GetJobArtifact returns the file produced by a finished job, or nil content
+// when there is none.
+func GetJobArtifact(id string, userID int) (string, string, []byte, error) {
+	var name, contentType sql.NullString
+	var content []byte
+	err := DB.QueryRow("SELECT artifact_name, artifact_type, artifact FROM job WHERE id = ? AND user_id = ? AND status = ?",
+		id, userID, types.JobDone).Scan(&name, &contentType, &content)
+	if err == sql.ErrNoRows {
+		return "", "", nil, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving artifact of job %s: %v", id, err)
+		return "", "", nil, err
+	}
+	return name.String, contentType.String, content, nil
+}
+
+// ClaimJob marks the oldest queued job as running and returns it, or nil when
+// the queue is empty. Workers racing for the same job are resolved by the
+// conditional update.
+func ClaimJob() (*types.JobTask, error) {
+	for {
+		var id string
+		err := DB.QueryRow("SELECT id FROM job WHERE status = ? ORDER BY created_at, id LIMIT 1", types.JobQueued).Scan(&id)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			log.Printf("Error retrieving queued job: %v", err)
+			return nil, err
+		}
+
+		result, err := DB.Exec("UPDATE job SET status = ?, progress = 0, started_at = "+sqlDialect.now+", renewed_at = "+sqlDialect.now+" WHERE id = ? AND status = ?",
+			types.JobRunning, id, types.JobQueued)
+		if err != nil {
+			log.Printf("Error claiming job %s: %v", id, err)
+			return nil, err
+		}
+		if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+			continue
+		}
+
+		task := types.JobTask{ID: id}
+		var params string
+		err = DB.QueryRow("SELECT user_id, kind, params, input FROM job WHERE id = ?", id).
+			Scan(&task.UserID, &task.Kind, &params, &task.Input)
+		if err != nil {
+			log.Printf("Error loading job %s: %v", id, err)
+			releaseJob(id)
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(params), &task.Params); err != nil {
+			log.Printf("Error decoding params of job %s: %v", id, err)
+			FailJob(id, "Internal error")
+			return nil, err
+		}
+		return &task, nil
+	}
+}
+
+// RenewJob records a running job's progress and renews its lease.
+func RenewJob(id string, progress int) error {
+	_, err := DB.Exec("UPDATE job SET progress = ?, renewed_at = "+sqlDialect.now+" WHERE id = ? AND status = ?",
+		progress, id, types.JobRunning)
+	if err != nil {
+		log.Printf("Error renewing job %s: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// FinishJob records a job's result and artifact and frees its input.
+func FinishJob(id string, result any, artifactName, artifactType string, artifact []byte) error {
+	var encoded []byte
+	if result != nil {
+		var err error
+		if encoded, err = json.Marshal(result); err != nil {
+			return err
+		}
+	}
+	var name, contentType any
+	if artifact != nil {
+		name, contentType = artifactName, artifactType
+	}
+	_, err := DB.Exec(`UPDATE job SET status = ?, progress = 100, result = ?, artifact = ?, artifact_name = ?,
+		artifact_type = ?, input = NULL, finished_at = `+sqlDialect.now+` WHERE id = ?`,
+		types.JobDone, nullableString(encoded), artifact, name, contentType, id)
+	if err != nil {
+		log.Printf("Error finishing job %s: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+func FailJob(id string, message string) error {
+	_, err := DB.Exec("UPDATE job SET status = ?, error = ?, input = NULL, finished_at = "+sqlDialect.now+" WHERE id = ?",
+		types.JobFailed, message, id)
+	if err != nil {
+		log.Printf("Error failing job %s: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// releaseJob puts a job claimed by this worker back in the queue.
+func releaseJob(id string) {
+	_, err := DB.Exec("UPDATE job SET status = ?, progress = 0, started_at = NULL, renewed_at = NULL WHERE id = ? AND status = ?",
+		types.JobQueued, id, types.JobRunning)
+	if err != nil {
+		log.Printf("Error releasing job %s: %v", id, err)
+	}
+}
+
+// RequeueStaleJobs puts running jobs whose lease was last renewed longer than
+// lease ago back in the queue. Their worker is taken to have died; jobs other
+// workers are still running renew their lease and are left alone.
+func RequeueStaleJobs(lease time.Duration) (int64, error) {
+	result, err := DB.Exec(`UPDATE job SET status = ?, progress = 0, started_at = NULL, renewed_at = NULL
+		WHERE status = ? AND COALESCE(renewed_at, started_at) < ?`,
+		types.JobQueued, types.JobRunning, sqlTime(time.Now().Add(-lease)))
+	if err != nil {
+		log.Printf("Error requeueing jobs: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func nullableString(b []byte) any {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
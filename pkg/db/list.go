@@ -0,0 +1,270 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not produced by the same
+// listing and sort.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// unscheduled sorts items without a due date after every scheduled one.
+const unscheduled = "9999-12-31 23:59:59"
+
+// sortKey is one order of a listing: the columns compared in turn, with the
+// row ID as the final tie breaker, and how to read their values from a row
+// for the next page's cursor.
+type sortKey[T any] struct {
+	columns []string
+	values  func(T) []any
+}
+
+// cursor marks the last row of a page. Pages continue after it, so rows
+// inserted or deleted meanwhile do not shift later pages.
+type cursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Values []any  `json:"v"`
+	ID     int    `json:"id"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string, filter types.ListFilter, columns int) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var c cursor
+	if err := decoder.Decode(&c); err != nil || c.Sort != filter.Sort || c.Desc != filter.Desc || len(c.Values) != columns {
+		return nil, ErrInvalidCursor
+	}
+	// Sort keys are booleans, integers and strings; anything else did not
+	// come from a page.
+	for i, value := range c.Values {
+		switch value := value.(type) {
+		case bool, string:
+		case json.Number:
+			n, err := value.Int64()
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			c.Values[i] = n
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &c, nil
+}
+
+// likePattern matches query anywhere in a value with sqlDialect.like, taking
+// its %, _ and \ literally.
+func likePattern(query string) string {
+	return "%" + likeEscaper.Replace(query) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// listPage runs a listing query one page at a time. from selects the rows of
+// the listing as a derived table; conditions filter its columns. It returns
+// the page and the cursor of the next one, or "" on the last page.
+func listPage[T any](from string, args []any, conditions []string, filter types.ListFilter, key sortKey[T],
+	scan func(*sql.Rows) (T, error), id func(T) int) ([]T, string, error) {
+	columns := append(append([]string{}, key.columns...), "id")
+	direction, compare := "ASC", ">"
+	if filter.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor, filter, len(key.columns))
+		if err != nil {
+			return nil, "", err
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		conditions = append(conditions, "("+strings.Join(columns, ", ")+") "+compare+" ("+placeholders+")")
+		args = append(append(args, c.Values...), c.ID)
+	}
+
+	query := from
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	order := make([]string, len(columns))
+	for i, column := range columns {
+		order[i] = column + " " + direction
+	}
+	query += " ORDER BY " + strings.Join(order, ", ") + " LIMIT ?"
+	args = append(args, filter.Limit+1)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error retrieving listing: %v", err)
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			log.Printf("Error scanning listing row: %v", err)
+			return nil, "", err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(items) <= filter.Limit {
+		return items, "", nil
+	}
+
+	items = items[:filter.Limit]
+	last := items[len(items)-1]
+	next := cursor{Sort: filter.Sort, Desc: filter.Desc, Values: key.values(last), ID: id(last)}
+	return items, next.encode(), nil
+}
+
+// idList returns the placeholders and arguments for an IN list of IDs.
+func idList(ids []int) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+func dueKey(dueAt *string) string {
+	if dueAt == nil {
+		return unscheduled
+	}
+	return *dueAt
+}
+
+var deckSortKeys = map[string]sortKey[types.Deck]{
+	// Owned decks come first in the user's order, then shared decks in their
+	// owners' order.
+	types.SortPosition: {[]string{"shared", "position"}, func(d types.Deck) []any { return []any{d.Role != types.RoleOwner, d.Position} }},
+	types.SortCreated:  {[]string{"created_at"}, func(d types.Deck) []any { return []any{d.CreatedAt} }},
+	types.SortUpdated:  {[]string{"updated_at"}, func(d types.Deck) []any { return []any{d.UpdatedAt} }},
+	types.SortDue:      {[]string{"due_key"}, func(d types.Deck) []any { return []any{dueKey(d.NextDueAt)} }},
+	types.SortAlpha:    {[]string{"name"}, func(d types.Deck) []any { return []any{d.Name} }},
+}
+
+// ListDecks returns a page of the decks the user owns or that are shared with
+// them, each with a summary of its cards from the user's point of view. Cards
+// count as learning until their second successful review; learning and review
+// counts include cards due by the end of today (UTC). NextDueAt is the earliest
+// due date of the user's reviewed cards.
+func ListDecks(userID int, filter types.ListFilter) ([]types.Deck, string, error) {
+	from := `SELECT id, user_id, name, created_at, updated_at, role, position, next_due,
+			card_count, new_count, learning_count, review_count, last_studied FROM (
+			SELECT d.id, d.user_id, d.name, d.created_at, COALESCE(d.updated_at, d.created_at) AS updated_at,
+				COALESCE(s.role, 'owner') AS role, d.position, s.user_id IS NOT NULL AS shared, n.next_due,
+				COALESCE(n.next_due, '` + unscheduled + `') AS due_key, COALESCE(n.card_count, 0) AS card_count,
+				COALESCE(n.new_count, 0) AS new_count, COALESCE(n.learning_count, 0) AS learning_count,
+				COALESCE(n.review_count, 0) AS review_count, n.last_studied
+			FROM deck d
+			LEFT JOIN deck_share s ON s.deck_id = d.id AND s.user_id = ?
+			LEFT JOIN (SELECT c.deck_id, COUNT(*) AS card_count,
+					SUM(CASE WHEN cs.card_id IS NULL THEN 1 ELSE 0 END) AS new_count,
+					SUM(CASE WHEN cs.reps < 2 AND cs.due_at < ` + sqlDialect.tomorrow + ` THEN 1 ELSE 0 END) AS learning_count,
+					SUM(CASE WHEN cs.reps >= 2 AND cs.due_at < ` + sqlDialect.tomorrow + ` THEN 1 ELSE 0 END) AS review_count,
+					MIN(cs.due_at) AS next_due, MAX(cs.last_reviewed_at) AS last_studied
+				FROM card c
+				LEFT JOIN card_state cs ON cs.card_id = c.id AND cs.user_id = ?
+				WHERE c.deleted_at IS NULL AND c.deck_id IN (
+					SELECT id FROM deck WHERE user_id = ? UNION SELECT deck_id FROM deck_share WHERE user_id = ?)
+				GROUP BY c.deck_id) n ON n.deck_id = d.id
+			WHERE d.deleted_at IS NULL AND (d.user_id = ? OR s.user_id IS NOT NULL)
+		) l`
+	args := []any{userID, userID, userID, userID, userID}
+
+	var conditions []string
+	if filter.Query != "" {
+		conditions = append(conditions, "name "+sqlDialect.like)
+		args = append(args, likePattern(filter.Query))
+	}
+	if filter.Role != "" {
+		conditions = append(conditions, "role = ?")
+		args = append(args, filter.Role)
+	}
+
+	return listPage(from, args, conditions, filter, deckSortKeys[filter.Sort], func(rows *sql.Rows) (types.Deck, error) {
+		var deck types.Deck
+		var nextDue, lastStudied sql.NullString
+		err := rows.Scan(&deck.ID, &deck.UserID, &deck.Name, &deck.CreatedAt, &deck.UpdatedAt, &deck.Role, &deck.Position, &nextDue,
+			&deck.CardCount, &deck.NewCount, &deck.LearningCount, &deck.ReviewCount, &lastStudied)
+		if nextDue.Valid {
+			deck.NextDueAt = &nextDue.String
+		}
+		if lastStudied.Valid {
+			deck.LastStudiedAt = &lastStudied.String
+		}
+		return deck, err
+	}, func(d types.Deck) int { return d.ID })
+}
+
+var cardSortKeys = map[string]sortKey[types.Card]{
+	types.SortPosition: {[]string{"position"}, func(c types.Card) []any { return []any{c.Position} }},
+	types.SortCreated:  {[]string{"created_at"}, func(c types.Card) []any { return []any{c.CreatedAt} }},
+	types.SortUpdated:  {[]string{"updated_at"}, func(c types.Card) []any { return []any{c.UpdatedAt} }},
+	types.SortDue:      {[]string{"due_key"}, func(c types.Card) []any { return []any{dueKey(c.DueAt)} }},
+	types.SortAlpha:    {[]string{"question"}, func(c types.Card) []any { return []any{c.Question} }},
+}
+
+// ListCards returns a page of a deck's cards with the user's due dates. Cards
+// the user has not reviewed yet have no due date.
+func ListCards(userID, deckID int, filter types.ListFilter) ([]types.Card, string, error) {
+	from := `SELECT id, deck_id, question, answer, created_at, updated_at, position, due_at FROM (
+			SELECT c.id, c.deck_id, c.question, c.answer, c.created_at, COALESCE(c.updated_at, c.created_at) AS updated_at,
+				c.position, cs.due_at, COALESCE(cs.due_at, '` + unscheduled + `') AS due_key
+			FROM card c
+			LEFT JOIN card_state cs ON cs.card_id = c.id AND cs.user_id = ?
+			WHERE c.deck_id = ? AND c.deleted_at IS NULL
+		) l`
+	args := []any{userID, deckID}
+
+	var conditions []string
+	if filter.Query != "" {
+		conditions = append(conditions, "(question "+sqlDialect.like+" OR answer "+sqlDialect.like+")")
+		pattern := likePattern(filter.Query)
+		args = append(args, pattern, pattern)
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, "id IN (SELECT card_id FROM card_tag WHERE tag = ?)")
+		args = append(args, filter.Tag)
+	}
+	switch filter.Status {
+	case types.CardStatusNew:
+		conditions = append(conditions, "due_at IS NULL")
+	case types.CardStatusReview:
+		conditions = append(conditions, "due_at IS NOT NULL")
+	case types.CardStatusDue:
+		conditions = append(conditions, "due_at <= "+sqlDialect.now)
+	}
+
+	return listPage(from, args, conditions, filter, cardSortKeys[filter.Sort], func(rows *sql.Rows) (types.Card, error) {
+		var card types.Card
+		var dueAt sql.NullString
+		err := rows.Scan(&card.ID, &card.DeckID, &card.Question, &card.Answer, &card.CreatedAt, &card.UpdatedAt, &card.Position, &dueAt)
+		if dueAt.Valid {
+			card.DueAt = &dueAt.String
+		}
+		return card, err
+	}, func(c types.Card) int { return c.ID })
+}
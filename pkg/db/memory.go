@@ -0,0 +1,485 @@
+package db
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-flashcards-server/pkg/dedupe"
+	"go-flashcards-server/pkg/types"
+)
+
+// ErrEmailTaken is returned by MemoryStore for a second account with the same
+// email, as the unique index on user.email does in the database.
+var ErrEmailTaken = errors.New("email already registered")
+
+// MemoryStore is a Store held in memory, for exercising handlers without a
+// database. It mirrors the database's behavior for users, decks and cards,
+// including shares, classroom assignments and the trash, but keeps no review
+// state: every card is new to every user.
+type MemoryStore struct {
+	mu       sync.Mutex
+	nextID   int
+	users    map[int]types.User
+	decks    map[int]*memoryDeck
+	cards    map[int]*memoryCard
+	shares   map[int]map[int]types.DeckShare
+	assigned map[int]map[int]bool
+}
+
+type memoryDeck struct {
+	deck    types.Deck
+	deleted bool
+}
+
+type memoryCard struct {
+	card    types.Card
+	tags    []string
+	deleted bool
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:    map[int]types.User{},
+		decks:    map[int]*memoryDeck{},
+		cards:    map[int]*memoryCard{},
+		shares:   map[int]map[int]types.DeckShare{},
+		assigned: map[int]map[int]bool{},
+	}
+}
+
+// Assign lets userID study a deck, as assigning it to a classroom the user
+// belongs to does.
+func (s *MemoryStore) Assign(deckID, userID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.assigned[deckID] == nil {
+		s.assigned[deckID] = map[int]bool{}
+	}
+	s.assigned[deckID][userID] = true
+}
+
+func (s *MemoryStore) id() int {
+	s.nextID++
+	return s.nextID
+}
+
+func memoryNow() string {
+	return time.Now().UTC().Format(time.DateTime)
+}
+
+func (s *MemoryStore) CreateUser(user types.User) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.users {
+		if strings.EqualFold(existing.Email, user.Email) {
+			return 0, ErrEmailTaken
+		}
+	}
+	user.ID = s.id()
+	s.users[user.ID] = user
+	return int64(user.ID), nil
+}
+
+func (s *MemoryStore) GetUserByEmail(email string) (*types.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+// nextPosition mirrors orderedList.next: trashed rows count too.
+func nextPosition[T any](rows map[int]T, inList func(T) bool, position func(T) int64) int64 {
+	var last int64
+	for _, row := range rows {
+		if inList(row) && position(row) > last {
+			last = position(row)
+		}
+	}
+	return last + positionGap
+}
+
+func (s *MemoryStore) CreateDeck(userID int, name string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	position := nextPosition(s.decks, func(d *memoryDeck) bool { return d.deck.UserID == userID },
+		func(d *memoryDeck) int64 { return d.deck.Position })
+	created := memoryNow()
+	deck := types.Deck{ID: s.id(), UserID: userID, Name: name, CreatedAt: created, UpdatedAt: created, Position: position}
+	s.decks[deck.ID] = &memoryDeck{deck: deck}
+	return int64(deck.ID), nil
+}
+
+func (s *MemoryStore) ListDecks(userID int, filter types.ListFilter) ([]types.Deck, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	decks := []types.Deck{}
+	for _, d := range s.decks {
+		role := s.collectionRole(d.deck.ID, userID)
+		if role == "" || (filter.Role != "" && role != filter.Role) || !contains(d.deck.Name, filter.Query) {
+			continue
+		}
+		deck := d.deck
+		deck.Role = role
+		for _, c := range s.cards {
+			if c.card.DeckID == deck.ID && !c.deleted {
+				deck.CardCount++
+				deck.NewCount++
+			}
+		}
+		decks = append(decks, deck)
+	}
+	return memoryPage(decks, filter, deckSortKeys[filter.Sort], func(d types.Deck) int { return d.ID })
+}
+
+func (s *MemoryStore) UpdateDeck(deckID int, name string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.decks[deckID]
+	if !ok || d.deleted {
+		return 0, nil
+	}
+	d.deck.Name = name
+	d.deck.UpdatedAt = memoryNow()
+	return 1, nil
+}
+
+func (s *MemoryStore) DeleteDeck(deckID, targetDeckID int) (int64, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.decks[deckID]
+	if !ok || d.deleted {
+		return 0, 0, nil
+	}
+	d.deleted = true
+
+	moved := 0
+	if targetDeckID != 0 {
+		for _, cardID := range slices.Sorted(maps.Keys(s.cards)) {
+			c := s.cards[cardID]
+			if c.card.DeckID != deckID || c.deleted {
+				continue
+			}
+			c.card.Position = s.nextCardPosition(targetDeckID)
+			c.card.DeckID = targetDeckID
+			moved++
+		}
+	}
+	return 1, moved, nil
+}
+
+func (s *MemoryStore) GetDeckRole(deckID, userID int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.role(deckID, userID), nil
+}
+
+func (s *MemoryStore) role(deckID, userID int) string {
+	if role := s.collectionRole(deckID, userID); role != "" {
+		return role
+	}
+	if d, ok := s.decks[deckID]; ok && !d.deleted && s.assigned[deckID][userID] {
+		return types.RoleViewer
+	}
+	return ""
+}
+
+// collectionRole is the user's role on a deck they own or that is shared
+// with them: the decks listed as theirs, leaving out assigned decks.
+func (s *MemoryStore) collectionRole(deckID, userID int) string {
+	d, ok := s.decks[deckID]
+	if !ok || d.deleted {
+		return ""
+	}
+	if d.deck.UserID == userID {
+		return types.RoleOwner
+	}
+	return s.shares[deckID][userID].Role
+}
+
+func (s *MemoryStore) ShareDeck(deckID, userID int, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shares[deckID] == nil {
+		s.shares[deckID] = map[int]types.DeckShare{}
+	}
+	share, ok := s.shares[deckID][userID]
+	if !ok {
+		share = types.DeckShare{DeckID: deckID, UserID: userID, CreatedAt: memoryNow()}
+	}
+	share.Role = role
+	s.shares[deckID][userID] = share
+	return nil
+}
+
+func (s *MemoryStore) UnshareDeck(deckID, userID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.shares[deckID][userID]; !ok {
+		return 0, nil
+	}
+	delete(s.shares[deckID], userID)
+	return 1, nil
+}
+
+func (s *MemoryStore) GetDeckShares(deckID int) ([]types.DeckShare, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shares := []types.DeckShare{}
+	for _, userID := range slices.Sorted(maps.Keys(s.shares[deckID])) {
+		share := s.shares[deckID][userID]
+		share.Email = s.users[userID].Email
+		shares = append(shares, share)
+	}
+	return shares, nil
+}
+
+func (s *MemoryStore) nextCardPosition(deckID int) int64 {
+	return nextPosition(s.cards, func(c *memoryCard) bool { return c.card.DeckID == deckID },
+		func(c *memoryCard) int64 { return c.card.Position })
+}
+
+func (s *MemoryStore) CreateCard(deckID int, question, answer string, tags []string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createCard(deckID, question, answer, tags), nil
+}
+
+func (s *MemoryStore) createCard(deckID int, question, answer string, tags []string) int64 {
+	created := memoryNow()
+	card := types.Card{ID: s.id(), DeckID: deckID, Question: question, Answer: answer,
+		CreatedAt: created, UpdatedAt: created, Position: s.nextCardPosition(deckID)}
+	s.cards[card.ID] = &memoryCard{card: card, tags: sortedTags(tags)}
+	return int64(card.ID)
+}
+
+func sortedTags(tags []string) []string {
+	tags = slices.Clone(tags)
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+func (s *MemoryStore) ListCards(userID, deckID int, filter types.ListFilter) ([]types.Card, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cards := []types.Card{}
+	for _, c := range s.cards {
+		if c.card.DeckID != deckID || c.deleted {
+			continue
+		}
+		if filter.Query != "" && !contains(c.card.Question, filter.Query) && !contains(c.card.Answer, filter.Query) {
+			continue
+		}
+		if filter.Tag != "" && !slices.Contains(c.tags, filter.Tag) {
+			continue
+		}
+		// No card has been reviewed, so none is scheduled.
+		if filter.Status == types.CardStatusReview || filter.Status == types.CardStatusDue {
+			continue
+		}
+		cards = append(cards, c.card)
+	}
+	return memoryPage(cards, filter, cardSortKeys[filter.Sort], func(c types.Card) int { return c.ID })
+}
+
+func (s *MemoryStore) GetCardDeckID(cardID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.cards[cardID]
+	if !ok || c.deleted {
+		return 0, nil
+	}
+	return c.card.DeckID, nil
+}
+
+func (s *MemoryStore) GetCardStates(userID int, cardIDs []int) (map[int]types.CardState, error) {
+	return map[int]types.CardState{}, nil
+}
+
+func (s *MemoryStore) GetCardTags(cardIDs []int) (map[int][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tags := map[int][]string{}
+	for _, id := range cardIDs {
+		if c, ok := s.cards[id]; ok && len(c.tags) > 0 {
+			tags[id] = slices.Clone(c.tags)
+		}
+	}
+	return tags, nil
+}
+
+func (s *MemoryStore) GetDuplicateIndex(userID, deckID int, policy types.DuplicatePolicy) (*dedupe.Index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := dedupe.NewIndex(policy.Similarity)
+	for _, cardID := range slices.Sorted(maps.Keys(s.cards)) {
+		c := s.cards[cardID]
+		if c.deleted {
+			continue
+		}
+		if policy.Scope == types.DuplicateScopeCollection {
+			if s.collectionRole(c.card.DeckID, userID) == "" {
+				continue
+			}
+		} else if c.card.DeckID != deckID {
+			continue
+		}
+		index.Add(types.DuplicateCard{ID: c.card.ID, DeckID: c.card.DeckID, Question: c.card.Question, Answer: c.card.Answer})
+	}
+	return index, nil
+}
+
+// UpdateCard edits a card. Revisions are not kept.
+func (s *MemoryStore) UpdateCard(cardID, userID int, question, answer string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.updateCard(cardID, question, answer), nil
+}
+
+func (s *MemoryStore) updateCard(cardID int, question, answer string) bool {
+	c, ok := s.cards[cardID]
+	if !ok || c.deleted {
+		return false
+	}
+	if c.card.Question != question || c.card.Answer != answer {
+		c.card.Question, c.card.Answer = question, answer
+		c.card.UpdatedAt = memoryNow()
+	}
+	return true
+}
+
+func (s *MemoryStore) DeleteCard(cardID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteCard(cardID), nil
+}
+
+func (s *MemoryStore) deleteCard(cardID int) int64 {
+	c, ok := s.cards[cardID]
+	if !ok || c.deleted {
+		return 0
+	}
+	c.deleted = true
+	return 1
+}
+
+// ApplyBulk runs validated card operations, as ApplyBulk does.
+func (s *MemoryStore) ApplyBulk(userID int, ops []types.BulkOperation) ([]types.BulkResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]types.BulkResult, len(ops))
+	for i, op := range ops {
+		result := types.BulkResult{Index: i, Op: op.Op, OK: true, CardID: op.CardID}
+		switch op.Op {
+		case types.BulkCreate:
+			result.CardID = int(s.createCard(op.DeckID, op.Question, op.Answer, op.Tags))
+		case types.BulkUpdate:
+			s.updateCard(op.CardID, op.Question, op.Answer)
+			if op.Tags != nil {
+				s.cards[op.CardID].tags = sortedTags(op.Tags)
+			}
+		case types.BulkDelete:
+			s.deleteCard(op.CardID)
+		case types.BulkMove:
+			c := s.cards[op.CardID]
+			c.card.Position = s.nextCardPosition(op.DeckID)
+			c.card.DeckID = op.DeckID
+		default:
+			return nil, fmt.Errorf("unknown bulk operation %q", op.Op)
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// contains matches like LIKE '%substr%' under a case-insensitive collation.
+func contains(value, substr string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(substr))
+}
+
+// memoryPage orders and pages items the way listPage does in the database.
+func memoryPage[T any](items []T, filter types.ListFilter, key sortKey[T], id func(T) int) ([]T, string, error) {
+	less := func(a, b []any) int {
+		for i := range a {
+			if c := compareValues(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	keyOf := func(item T) []any { return append(key.values(item), int64(id(item))) }
+	sort.SliceStable(items, func(i, j int) bool {
+		c := less(keyOf(items[i]), keyOf(items[j]))
+		if filter.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor, filter, len(key.columns))
+		if err != nil {
+			return nil, "", err
+		}
+		after := append(c.Values, int64(c.ID))
+		start := len(items)
+		for i, item := range items {
+			order := less(keyOf(item), after)
+			if (!filter.Desc && order > 0) || (filter.Desc && order < 0) {
+				start = i
+				break
+			}
+		}
+		items = items[start:]
+	}
+	if len(items) <= filter.Limit {
+		return items, "", nil
+	}
+
+	items = items[:filter.Limit]
+	last := items[len(items)-1]
+	next := cursor{Sort: filter.Sort, Desc: filter.Desc, Values: key.values(last), ID: id(last)}
+	return items, next.encode(), nil
+}
+
+// compareValues compares sort key values as they come from rows and from
+// decoded cursors: booleans, integers and strings.
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case bool:
+		b, _ := b.(bool)
+		switch {
+		case a == b:
+			return 0
+		case !a:
+			return -1
+		}
+		return 1
+	case string:
+		b, _ := b.(string)
+		return cmp.Compare(a, b)
+	}
+	return cmp.Compare(toInt64(a), toInt64(b))
+}
+
+func toInt64(value any) int64 {
+	switch value := value.(type) {
+	case int:
+		return int64(value)
+	case int64:
+		return value
+	}
+	return 0
+}
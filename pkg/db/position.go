@@ -0,0 +1,202 @@
+package db
+
+import (
+	"log"
+	"slices"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// Decks and cards are ordered by position, with positionGap between
+// neighbours so moving one item usually rewrites only that item. When two
+// neighbours have no room left between them the whole list is renumbered.
+// Rows created before positions existed share position 0 and fall back to ID
+// order.
+const positionGap = 1024
+
+// orderedList is a list of positioned rows: the decks of an owner or the
+// cards of a deck.
+type orderedList struct {
+	table   string
+	scope   string
+	scopeID int
+}
+
+func deckList(userID int) orderedList { return orderedList{"deck", "user_id", userID} }
+
+func cardList(deckID int) orderedList { return orderedList{"card", "deck_id", deckID} }
+
+// next returns the position after the last row of the list.
+func (l orderedList) next(q querier) (int64, error) {
+	var position int64
+	err := q.QueryRow("SELECT COALESCE(MAX(position), 0) + ? FROM "+l.table+" WHERE "+l.scope+" = ?",
+		positionGap, l.scopeID).Scan(&position)
+	if err != nil {
+		log.Printf("Error retrieving next %s position: %v", l.table, err)
+		return 0, err
+	}
+	return position, nil
+}
+
+// load returns the list's rows that are not in the trash, in order.
+func (l orderedList) load(q querier) ([]types.ItemPosition, error) {
+	rows, err := q.Query("SELECT id, position FROM "+l.table+" WHERE "+l.scope+" = ? AND deleted_at IS NULL ORDER BY position, id",
+		l.scopeID)
+	if err != nil {
+		log.Printf("Error retrieving %s positions: %v", l.table, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []types.ItemPosition{}
+	for rows.Next() {
+		var item types.ItemPosition
+		if err := rows.Scan(&item.ID, &item.Position); err != nil {
+			log.Printf("Error scanning %s position: %v", l.table, err)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (l orderedList) set(q querier, id int, position int64) error {
+	if _, err := q.Exec("UPDATE "+l.table+" SET position = ? WHERE id = ?", position, id); err != nil {
+		log.Printf("Error positioning %s %d: %v", l.table, id, err)
+		return err
+	}
+	return nil
+}
+
+// move places id directly before or after anchorID. It returns nil when
+// either is not in the list.
+func (l orderedList) move(q querier, id, anchorID int, after bool) ([]types.ItemPosition, error) {
+	items, err := l.load(q)
+	if err != nil {
+		return nil, err
+	}
+	from := slices.IndexFunc(items, func(item types.ItemPosition) bool { return item.ID == id })
+	if from < 0 || id == anchorID {
+		return nil, nil
+	}
+	moving := items[from]
+	items = slices.Delete(items, from, from+1)
+	to := slices.IndexFunc(items, func(item types.ItemPosition) bool { return item.ID == anchorID })
+	if to < 0 {
+		return nil, nil
+	}
+	if after {
+		to++
+	}
+
+	var lower, upper int64
+	switch {
+	case len(items) == 0:
+		lower, upper = 0, 2*positionGap
+	case to == 0:
+		lower, upper = items[0].Position-2*positionGap, items[0].Position
+	case to == len(items):
+		lower, upper = items[to-1].Position, items[to-1].Position+2*positionGap
+	default:
+		lower, upper = items[to-1].Position, items[to].Position
+	}
+	items = slices.Insert(items, to, moving)
+	if upper-lower >= 2 {
+		items[to].Position = lower + (upper-lower)/2
+		return items, l.set(q, id, items[to].Position)
+	}
+	return items, l.renumber(q, items)
+}
+
+// reorder puts the list in the order of ids, which must hold every row of the
+// list exactly once. It returns nil when they do not.
+func (l orderedList) reorder(q querier, ids []int) ([]types.ItemPosition, error) {
+	items, err := l.load(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != len(items) {
+		return nil, nil
+	}
+	byID := map[int]types.ItemPosition{}
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+	ordered := make([]types.ItemPosition, 0, len(ids))
+	for _, id := range ids {
+		item, ok := byID[id]
+		if !ok {
+			return nil, nil
+		}
+		delete(byID, id)
+		ordered = append(ordered, item)
+	}
+	return ordered, l.renumber(q, ordered)
+}
+
+// renumber spaces items positionGap apart in their current order, updating
+// only rows whose position changes.
+func (l orderedList) renumber(q querier, items []types.ItemPosition) error {
+	for i := range items {
+		position := int64(i+1) * positionGap
+		if items[i].Position == position {
+			continue
+		}
+		items[i].Position = position
+		if err := l.set(q, items[i].ID, position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveDeck places one of the user's decks before or after another. It
+// returns nil when either deck is not one of theirs.
+func MoveDeck(userID, deckID, anchorID int, after bool) ([]types.ItemPosition, error) {
+	return positionTx(func(q querier) ([]types.ItemPosition, error) {
+		return deckList(userID).move(q, deckID, anchorID, after)
+	})
+}
+
+// ReorderDecks orders the user's decks as given by ids, which must list each
+// of them once. It returns nil when they do not.
+func ReorderDecks(userID int, ids []int) ([]types.ItemPosition, error) {
+	return positionTx(func(q querier) ([]types.ItemPosition, error) {
+		return deckList(userID).reorder(q, ids)
+	})
+}
+
+// MoveCardPosition places a card before or after another card of the same
+// deck. It returns nil when either card is not in the deck.
+func MoveCardPosition(deckID, cardID, anchorID int, after bool) ([]types.ItemPosition, error) {
+	return positionTx(func(q querier) ([]types.ItemPosition, error) {
+		return cardList(deckID).move(q, cardID, anchorID, after)
+	})
+}
+
+// ReorderCards orders a deck's cards as given by ids, which must list each of
+// them once. It returns nil when they do not.
+func ReorderCards(deckID int, ids []int) ([]types.ItemPosition, error) {
+	return positionTx(func(q querier) ([]types.ItemPosition, error) {
+		return cardList(deckID).reorder(q, ids)
+	})
+}
+
+func positionTx(fn func(q querier) ([]types.ItemPosition, error)) ([]types.ItemPosition, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	items, err := fn(tx)
+	if err != nil || items == nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing new order: %v", err)
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"go-flashcards-server/pkg/srs"
+	"go-flashcards-server/pkg/types"
+)
+
+const stateColumns = "card_id, due_at, interval_days, ease, reps, lapses, last_reviewed_at"
+
+func scanCardState(scanner interface{ Scan(...any) error }) (types.CardState, error) {
+	var state types.CardState
+	var lastReviewedAt sql.NullString
+	err := scanner.Scan(&state.CardID, &state.DueAt, &state.IntervalDays, &state.Ease, &state.Reps, &state.Lapses, &lastReviewedAt)
+	if lastReviewedAt.Valid {
+		state.LastReviewedAt = &lastReviewedAt.String
+	}
+	return state, err
+}
+
+// GetCardState returns the user's scheduling state for a card, or nil when the
+// user has never reviewed it.
+func GetCardState(userID, cardID int) (*types.CardState, error) {
+	query := "SELECT " + stateColumns + " FROM card_state WHERE user_id = ? AND card_id = ?"
+	state, err := scanCardState(DB.QueryRow(query, userID, cardID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving state for card %d: %v", cardID, err)
+		return nil, err
+	}
+	return &state, nil
+}
+
+// GetCardStatesByDeck returns the user's scheduling state for every reviewed
+// card of a deck, keyed by card ID.
+func GetCardStatesByDeck(userID, deckID int) (map[int]types.CardState, error) {
+	query := `SELECT cs.card_id, cs.due_at, cs.interval_days, cs.ease, cs.reps, cs.lapses, cs.last_reviewed_at
+		FROM card_state cs JOIN card c ON c.id = cs.card_id
+		WHERE cs.user_id = ? AND c.deck_id = ?`
+	rows, err := DB.Query(query, userID, deckID)
+	if err != nil {
+		log.Printf("Error retrieving card states for deck %d: %v", deckID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := map[int]types.CardState{}
+	for rows.Next() {
+		state, err := scanCardState(rows)
+		if err != nil {
+			log.Printf("Error scanning card state row: %v", err)
+			return nil, err
+		}
+		states[state.CardID] = state
+	}
+	return states, nil
+}
+
+// GetCardStates returns the user's scheduling state for those of the cards
+// they have reviewed, keyed by card ID.
+func GetCardStates(userID int, cardIDs []int) (map[int]types.CardState, error) {
+	states := map[int]types.CardState{}
+	if len(cardIDs) == 0 {
+		return states, nil
+	}
+	placeholders, args := idList(cardIDs)
+	query := `SELECT card_id, due_at, interval_days, ease, reps, lapses, last_reviewed_at
+		FROM card_state WHERE user_id = ? AND card_id IN (` + placeholders + `)`
+	rows, err := DB.Query(query, append([]any{userID}, args...)...)
+	if err != nil {
+		log.Printf("Error retrieving card states: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		state, err := scanCardState(rows)
+		if err != nil {
+			log.Printf("Error scanning card state row: %v", err)
+			return nil, err
+		}
+		states[state.CardID] = state
+	}
+	return states, rows.Err()
+}
+
+// RecordReview applies a graded review to the user's state for a card and
+// appends it to the review log.
+func RecordReview(userID, cardID, grade int) (*types.CardState, error) {
+	current, err := GetCardState(userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	state := srs.NewState()
+	if current != nil {
+		state = srs.State{
+			IntervalDays: current.IntervalDays,
+			Ease:         current.Ease,
+			Reps:         current.Reps,
+			Lapses:       current.Lapses,
+		}
+	}
+	state = srs.Next(state, grade)
+	now := time.Now().UTC()
+	dueAt := srs.DueAt(state, now)
+
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	insert := `INSERT INTO card_state (user_id, card_id, due_at, interval_days, ease, reps, lapses, last_reviewed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(sqlDialect.upsert(insert, []string{"user_id", "card_id"},
+		"due_at", "interval_days", "ease", "reps", "lapses", "last_reviewed_at"),
+		userID, cardID, sqlTime(dueAt), state.IntervalDays, state.Ease, state.Reps, state.Lapses, sqlTime(now))
+	if err != nil {
+		log.Printf("Error saving state for card %d: %v", cardID, err)
+		return nil, err
+	}
+
+	_, err = tx.Exec("INSERT INTO review_log (user_id, card_id, grade, interval_days, reviewed_at) VALUES (?, ?, ?, ?, ?)",
+		userID, cardID, grade, state.IntervalDays, sqlTime(now))
+	if err != nil {
+		log.Printf("Error logging review for card %d: %v", cardID, err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing review: %v", err)
+		return nil, err
+	}
+	return GetCardState(userID, cardID)
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// updateCard sets a card's question and answer on behalf of userID and
+// records the change as a new revision. Saving identical text records
+// nothing. It reports false when the card does not exist.
+func updateCard(q querier, cardID, userID int, question, answer string) (bool, error) {
+	var oldQuestion, oldAnswer string
+	err := q.QueryRow("SELECT question, answer FROM card WHERE id = ? AND deleted_at IS NULL", cardID).Scan(&oldQuestion, &oldAnswer)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving card %d: %v", cardID, err)
+		return false, err
+	}
+	if oldQuestion == question && oldAnswer == answer {
+		return true, nil
+	}
+
+	if _, err := q.Exec("UPDATE card SET question = ?, answer = ?, updated_at = "+sqlDialect.now+" WHERE id = ?", question, answer, cardID); err != nil {
+		log.Printf("Error updating card %d: %v", cardID, err)
+		return false, err
+	}
+	var rev int
+	if err := q.QueryRow("SELECT COALESCE(MAX(rev), 0) + 1 FROM card_revision WHERE card_id = ?", cardID).Scan(&rev); err != nil {
+		log.Printf("Error numbering revision of card %d: %v", cardID, err)
+		return false, err
+	}
+	_, err = q.Exec(`INSERT INTO card_revision (card_id, rev, user_id, question_before, answer_before, question_after, answer_after)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, cardID, rev, userID, oldQuestion, oldAnswer, question, answer)
+	if err != nil {
+		log.Printf("Error recording revision of card %d: %v", cardID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// GetCardRevisions returns a card's revisions, newest first.
+func GetCardRevisions(cardID int) ([]types.CardRevision, error) {
+	query := `SELECT r.rev, r.card_id, r.user_id, COALESCE(CONCAT_WS(' ', u.first_name, u.last_name), ''),
+			r.question_before, r.answer_before, r.question_after, r.answer_after, r.created_at
+		FROM card_revision r
+		LEFT JOIN user u ON u.id = r.user_id
+		WHERE r.card_id = ?
+		ORDER BY r.rev DESC`
+	rows, err := DB.Query(query, cardID)
+	if err != nil {
+		log.Printf("Error retrieving revisions of card %d: %v", cardID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []types.CardRevision{}
+	for rows.Next() {
+		var rev types.CardRevision
+		err := rows.Scan(&rev.Rev, &rev.CardID, &rev.UserID, &rev.UserName,
+			&rev.QuestionBefore, &rev.AnswerBefore, &rev.QuestionAfter, &rev.AnswerAfter, &rev.CreatedAt)
+		if err != nil {
+			log.Printf("Error scanning revision row: %v", err)
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// RevertCard undoes revision rev and every later one by restoring the text the
+// card had before rev. The revert is itself recorded as a revision. It returns
+// nil when the revision does not exist.
+func RevertCard(cardID, userID, rev int) (*types.Card, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var card types.Card
+	err = tx.QueryRow("SELECT question_before, answer_before FROM card_revision WHERE card_id = ? AND rev = ?",
+		cardID, rev).Scan(&card.Question, &card.Answer)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving revision %d of card %d: %v", rev, cardID, err)
+		return nil, err
+	}
+	found, err := updateCard(tx, cardID, userID, card.Question, card.Answer)
+	if err != nil || !found {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing revert of card %d: %v", cardID, err)
+		return nil, err
+	}
+	card.ID = cardID
+	return &card, nil
+}
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"log"
+)
+
+// schema holds every table, written for MySQL and adapted by the dialect.
+// Every statement must be safe to run on each startup.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS user (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		first_name VARCHAR(255) NOT NULL,
+		last_name VARCHAR(255) NOT NULL,
+		email VARCHAR(255) NOT NULL UNIQUE,
+		password_hash VARCHAR(255) NOT NULL,
+		salt VARCHAR(32) NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS deck (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_deck_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS card (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		deck_id INT NOT NULL,
+		question TEXT NOT NULL,
+		answer TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_card_deck (deck_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS deck_share (
+		deck_id INT NOT NULL,
+		user_id INT NOT NULL,
+		role VARCHAR(16) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (deck_id, user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS card_state (
+		user_id INT NOT NULL,
+		card_id INT NOT NULL,
+		due_at DATETIME NOT NULL,
+		interval_days INT NOT NULL DEFAULT 0,
+		ease DOUBLE NOT NULL DEFAULT 2.5,
+		reps INT NOT NULL DEFAULT 0,
+		lapses INT NOT NULL DEFAULT 0,
+		last_reviewed_at DATETIME NULL,
+		PRIMARY KEY (user_id, card_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS review_log (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		card_id INT NOT NULL,
+		grade INT NOT NULL,
+		interval_days INT NOT NULL,
+		reviewed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_review_log_user (user_id, reviewed_at)
+	)`,
+	`CREATE TABLE IF NOT EXISTS catalog_entry (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		deck_id INT NOT NULL UNIQUE,
+		user_id INT NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL,
+		language VARCHAR(16) NOT NULL DEFAULT '',
+		clone_count INT NOT NULL DEFAULT 0,
+		published_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS catalog_tag (
+		entry_id INT NOT NULL,
+		tag VARCHAR(64) NOT NULL,
+		PRIMARY KEY (entry_id, tag),
+		INDEX idx_catalog_tag_tag (tag)
+	)`,
+	`CREATE TABLE IF NOT EXISTS deck_fork (
+		deck_id INT PRIMARY KEY,
+		source_deck_id INT NOT NULL,
+		synced_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS card_fork (
+		deck_id INT NOT NULL,
+		source_card_id INT NOT NULL,
+		card_id INT NULL,
+		base_question TEXT NOT NULL,
+		base_answer TEXT NOT NULL,
+		PRIMARY KEY (deck_id, source_card_id),
+		INDEX idx_card_fork_card (card_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS classroom (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		owner_id INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		join_code VARCHAR(16) NOT NULL UNIQUE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS classroom_member (
+		classroom_id INT NOT NULL,
+		user_id INT NOT NULL,
+		role VARCHAR(16) NOT NULL,
+		joined_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (classroom_id, user_id),
+		INDEX idx_classroom_member_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS classroom_deck (
+		classroom_id INT NOT NULL,
+		deck_id INT NOT NULL,
+		due_at DATETIME NULL,
+		assigned_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (classroom_id, deck_id),
+		INDEX idx_classroom_deck_deck (deck_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS study_group (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		owner_id INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		invite_code VARCHAR(16) NOT NULL UNIQUE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS group_member (
+		group_id INT NOT NULL,
+		user_id INT NOT NULL,
+		hidden BOOLEAN NOT NULL DEFAULT FALSE,
+		joined_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (group_id, user_id),
+		INDEX idx_group_member_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS group_invite (
+		group_id INT NOT NULL,
+		user_id INT NOT NULL,
+		invited_by INT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (group_id, user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS group_challenge (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		group_id INT NOT NULL,
+		created_by INT NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		metric VARCHAR(16) NOT NULL,
+		target INT NOT NULL,
+		starts_at DATETIME NOT NULL,
+		ends_at DATETIME NOT NULL,
+		INDEX idx_group_challenge_group (group_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS card_tag (
+		card_id INT NOT NULL,
+		tag VARCHAR(64) NOT NULL,
+		PRIMARY KEY (card_id, tag),
+		INDEX idx_card_tag_tag (tag)
+	)`,
+	`CREATE TABLE IF NOT EXISTS import_upload (
+		id CHAR(32) PRIMARY KEY,
+		user_id INT NOT NULL,
+		filename VARCHAR(255) NOT NULL,
+		content LONGBLOB NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS job (
+		id CHAR(32) PRIMARY KEY,
+		user_id INT NOT NULL,
+		kind VARCHAR(16) NOT NULL,
+		status VARCHAR(16) NOT NULL,
+		progress INT NOT NULL DEFAULT 0,
+		params TEXT NOT NULL,
+		input LONGBLOB NULL,
+		result TEXT NULL,
+		error TEXT NULL,
+		artifact LONGBLOB NULL,
+		artifact_name VARCHAR(255) NULL,
+		artifact_type VARCHAR(128) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		started_at DATETIME NULL,
+		finished_at DATETIME NULL,
+		INDEX idx_job_status (status, created_at),
+		INDEX idx_job_user (user_id, created_at)
+	)`,
+	`CREATE TABLE IF NOT EXISTS card_revision (
+		card_id INT NOT NULL,
+		rev INT NOT NULL,
+		user_id INT NOT NULL,
+		question_before TEXT NOT NULL,
+		answer_before TEXT NOT NULL,
+		question_after TEXT NOT NULL,
+		answer_after TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (card_id, rev)
+	)`,
+}
+
+// columns holds columns added to the original tables. MySQL cannot add a
+// column only if it is missing, so migrate checks for it first.
+var columns = []struct{ table, name, definition string }{
+	{"deck", "deleted_at", "DATETIME NULL"},
+	{"card", "deleted_at", "DATETIME NULL"},
+	{"deck", "position", "BIGINT NOT NULL DEFAULT 0"},
+	{"card", "position", "BIGINT NOT NULL DEFAULT 0"},
+	{"deck", "updated_at", "DATETIME NULL"},
+	{"card", "updated_at", "DATETIME NULL"},
+	{"job", "renewed_at", "DATETIME NULL"},
+}
+
+func migrate() {
+	for _, table := range schema {
+		for _, statement := range sqlDialect.table(table) {
+			if _, err := DB.Exec(statement); err != nil {
+				log.Fatalf("Error migrating database: %v", err)
+			}
+		}
+	}
+	for _, column := range columns {
+		var count int
+		if err := DB.QueryRow(sqlDialect.columnExists, column.table, column.name).Scan(&count); err != nil {
+			log.Fatalf("Error migrating database: %v", err)
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := DB.Exec("ALTER TABLE " + column.table + " ADD COLUMN " + column.name + " " + sqlDialect.column(column.definition)); err != nil {
+			log.Fatalf("Error migrating database: %v", err)
+		}
+	}
+}
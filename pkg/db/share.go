@@ -0,0 +1,118 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"go-flashcards-server/pkg/types"
+)
+
+func GetUserIDByEmail(email string) (int, error) {
+	var userID int
+	err := DB.QueryRow("SELECT id FROM user WHERE email = ?", email).Scan(&userID)
+	if err != nil {
+		log.Printf("Error retrieving user with email %s: %v", email, err)
+		return 0, err
+	}
+	return userID, nil
+}
+
+// GetDeckRole returns the caller's role on a deck, or an empty string when the
+// deck does not exist, is in the trash or is not visible to the user.
+func GetDeckRole(deckID, userID int) (string, error) {
+	var ownerID int
+	err := DB.QueryRow("SELECT user_id FROM deck WHERE id = ? AND deleted_at IS NULL", deckID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving deck %d: %v", deckID, err)
+		return "", err
+	}
+	if ownerID == userID {
+		return types.RoleOwner, nil
+	}
+
+	var role string
+	err = DB.QueryRow("SELECT role FROM deck_share WHERE deck_id = ? AND user_id = ?", deckID, userID).Scan(&role)
+	if err == nil {
+		return role, nil
+	}
+	if err != sql.ErrNoRows {
+		log.Printf("Error retrieving share for deck %d: %v", deckID, err)
+		return "", err
+	}
+
+	// Students can study decks assigned to their classrooms.
+	assigned, err := IsDeckAssignedToUser(deckID, userID)
+	if err != nil {
+		return "", err
+	}
+	if assigned {
+		return types.RoleViewer, nil
+	}
+	return "", nil
+}
+
+// GetCardDeckID returns the deck a card belongs to, or 0 when the card does
+// not exist or is in the trash.
+func GetCardDeckID(cardID int) (int, error) {
+	var deckID int
+	err := DB.QueryRow("SELECT deck_id FROM card WHERE id = ? AND deleted_at IS NULL", cardID).Scan(&deckID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving card %d: %v", cardID, err)
+		return 0, err
+	}
+	return deckID, nil
+}
+
+func ShareDeck(deckID, userID int, role string) error {
+	query := sqlDialect.upsert("INSERT INTO deck_share (deck_id, user_id, role) VALUES (?, ?, ?)", []string{"deck_id", "user_id"}, "role")
+	_, err := DB.Exec(query, deckID, userID, role)
+	if err != nil {
+		log.Printf("Error sharing deck %d with user %d: %v", deckID, userID, err)
+		return err
+	}
+	return nil
+}
+
+func UnshareDeck(deckID, userID int) (int64, error) {
+	result, err := DB.Exec("DELETE FROM deck_share WHERE deck_id = ? AND user_id = ?", deckID, userID)
+	if err != nil {
+		log.Printf("Error unsharing deck %d from user %d: %v", deckID, userID, err)
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error retrieving rows affected: %v", err)
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+func GetDeckShares(deckID int) ([]types.DeckShare, error) {
+	query := `SELECT s.deck_id, s.user_id, u.email, s.role, s.created_at
+		FROM deck_share s JOIN user u ON u.id = s.user_id
+		WHERE s.deck_id = ?`
+	rows, err := DB.Query(query, deckID)
+	if err != nil {
+		log.Printf("Error retrieving shares for deck %d: %v", deckID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []types.DeckShare{}
+	for rows.Next() {
+		var share types.DeckShare
+		if err := rows.Scan(&share.DeckID, &share.UserID, &share.Email, &share.Role, &share.CreatedAt); err != nil {
+			log.Printf("Error scanning share row: %v", err)
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
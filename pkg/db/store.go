@@ -0,0 +1,94 @@
+package db
+
+import (
+	"go-flashcards-server/pkg/dedupe"
+	"go-flashcards-server/pkg/types"
+)
+
+// Store is the storage the user, deck and card handlers depend on. SQLStore
+// backs it with the database; MemoryStore keeps everything in memory so the
+// handlers can be exercised without one.
+type Store interface {
+	CreateUser(user types.User) (int64, error)
+	GetUserByEmail(email string) (*types.User, error)
+
+	CreateDeck(userID int, name string) (int64, error)
+	ListDecks(userID int, filter types.ListFilter) ([]types.Deck, string, error)
+	UpdateDeck(deckID int, name string) (int64, error)
+	DeleteDeck(deckID, targetDeckID int) (int64, int, error)
+	GetDeckRole(deckID, userID int) (string, error)
+	ShareDeck(deckID, userID int, role string) error
+	UnshareDeck(deckID, userID int) (int64, error)
+	GetDeckShares(deckID int) ([]types.DeckShare, error)
+
+	CreateCard(deckID int, question, answer string, tags []string) (int64, error)
+	ListCards(userID, deckID int, filter types.ListFilter) ([]types.Card, string, error)
+	GetCardDeckID(cardID int) (int, error)
+	GetCardStates(userID int, cardIDs []int) (map[int]types.CardState, error)
+	GetCardTags(cardIDs []int) (map[int][]string, error)
+	GetDuplicateIndex(userID, deckID int, policy types.DuplicatePolicy) (*dedupe.Index, error)
+	UpdateCard(cardID, userID int, question, answer string) (bool, error)
+	DeleteCard(cardID int) (int64, error)
+	ApplyBulk(userID int, ops []types.BulkOperation) ([]types.BulkResult, error)
+}
+
+// SQLStore is the Store over the database opened by Init.
+type SQLStore struct{}
+
+var _ Store = SQLStore{}
+
+func (SQLStore) CreateUser(user types.User) (int64, error) { return CreateUser(user) }
+
+func (SQLStore) GetUserByEmail(email string) (*types.User, error) { return GetUserByEmail(email) }
+
+func (SQLStore) CreateDeck(userID int, name string) (int64, error) { return CreateDeck(userID, name) }
+
+func (SQLStore) ListDecks(userID int, filter types.ListFilter) ([]types.Deck, string, error) {
+	return ListDecks(userID, filter)
+}
+
+func (SQLStore) UpdateDeck(deckID int, name string) (int64, error) { return UpdateDeck(deckID, name) }
+
+func (SQLStore) DeleteDeck(deckID, targetDeckID int) (int64, int, error) {
+	return DeleteDeck(deckID, targetDeckID)
+}
+
+func (SQLStore) GetDeckRole(deckID, userID int) (string, error) { return GetDeckRole(deckID, userID) }
+
+func (SQLStore) ShareDeck(deckID, userID int, role string) error {
+	return ShareDeck(deckID, userID, role)
+}
+
+func (SQLStore) UnshareDeck(deckID, userID int) (int64, error) { return UnshareDeck(deckID, userID) }
+
+func (SQLStore) GetDeckShares(deckID int) ([]types.DeckShare, error) { return GetDeckShares(deckID) }
+
+func (SQLStore) CreateCard(deckID int, question, answer string, tags []string) (int64, error) {
+	return CreateCard(deckID, question, answer, tags)
+}
+
+func (SQLStore) ListCards(userID, deckID int, filter types.ListFilter) ([]types.Card, string, error) {
+	return ListCards(userID, deckID, filter)
+}
+
+func (SQLStore) GetCardDeckID(cardID int) (int, error) { return GetCardDeckID(cardID) }
+
+func (SQLStore) GetCardStates(userID int, cardIDs []int) (map[int]types.CardState, error) {
+	return GetCardStates(userID, cardIDs)
+}
+
+func (SQLStore) GetCardTags(cardIDs []int) (map[int][]string, error) { return GetCardTags(cardIDs) }
+
+func (SQLStore) GetDuplicateIndex(userID, deckID int, policy types.DuplicatePolicy) (*dedupe.Index, error) {
+	return GetDuplicateIndex(userID, deckID, policy)
+}
+
+func (SQLStore) UpdateCard(cardID, userID int, question, answer string) (bool, error) {
+	return UpdateCard(cardID, userID, question, answer)
+}
+
+func (SQLStore) DeleteCard(cardID int) (int64, error) { return DeleteCard(cardID) }
+
+func (SQLStore) ApplyBulk(userID int, ops []types.BulkOperation) ([]types.BulkResult, error) {
+	return ApplyBulk(userID, ops)
+}
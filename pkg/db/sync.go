@@ -0,0 +1,133 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// SyncDeck applies the cards of a deck file in one transaction. A deckID of 0
+// creates a new deck for userID. Cards whose ID belongs to the deck are
+// updated in place, keeping their review state; other cards are created. With
+// prune set, cards of the deck missing from the file are moved to the trash.
+// progress is told how many cards are done before each one.
+func SyncDeck(userID, deckID int, name string, cards []types.SyncCard, prune bool, progress types.Progress) (*types.SyncReport, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := &types.SyncReport{DeckID: deckID, Name: name, Skipped: []types.ImportIssue{}}
+	if deckID == 0 {
+		id, err := insertDeck(tx, userID, name)
+		if err != nil {
+			return nil, err
+		}
+		report.DeckID = int(id)
+	} else if name != "" {
+		if _, err := tx.Exec("UPDATE deck SET name = ?, updated_at = "+sqlDialect.now+" WHERE id = ?", name, deckID); err != nil {
+			log.Printf("Error renaming deck %d: %v", deckID, err)
+			return nil, err
+		}
+	}
+
+	existing, err := loadCards(tx, report.DeckID)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := loadCardTags(tx, report.DeckID)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := map[int]bool{}
+	for i, card := range cards {
+		if progress != nil {
+			progress(i, len(cards))
+		}
+		current, ok := existing[card.ID]
+		if !ok {
+			if _, err := insertCard(tx, report.DeckID, card.Question, card.Answer, card.Tags); err != nil {
+				return nil, err
+			}
+			report.Created++
+			continue
+		}
+		if kept[card.ID] {
+			report.Skipped = append(report.Skipped, types.ImportIssue{Item: fmt.Sprintf("card %d", card.ID), Reason: "duplicate card id"})
+			continue
+		}
+		kept[card.ID] = true
+
+		if current.Question == card.Question && current.Answer == card.Answer && slices.Equal(tags[card.ID], card.Tags) {
+			report.Unchanged++
+			continue
+		}
+		if _, err := updateCard(tx, card.ID, userID, card.Question, card.Answer); err != nil {
+			return nil, err
+		}
+		if err := replaceCardTags(tx, card.ID, card.Tags); err != nil {
+			return nil, err
+		}
+		report.Updated++
+	}
+
+	if prune {
+		for _, id := range sortedCardIDs(existing) {
+			if kept[id] {
+				continue
+			}
+			if _, err := trashCard(tx, id); err != nil {
+				return nil, err
+			}
+			report.Deleted++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing deck sync: %v", err)
+		return nil, err
+	}
+	return report, nil
+}
+
+// loadCardTags returns the sorted tags of each tagged card in a deck.
+func loadCardTags(q querier, deckID int) (map[int][]string, error) {
+	rows, err := q.Query(`SELECT t.card_id, t.tag FROM card_tag t JOIN card c ON c.id = t.card_id
+		WHERE c.deck_id = ? AND c.deleted_at IS NULL ORDER BY t.card_id, t.tag`, deckID)
+	if err != nil {
+		log.Printf("Error retrieving tags for deck %d: %v", deckID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := map[int][]string{}
+	for rows.Next() {
+		var cardID int
+		var tag string
+		if err := rows.Scan(&cardID, &tag); err != nil {
+			log.Printf("Error scanning tag row: %v", err)
+			return nil, err
+		}
+		tags[cardID] = append(tags[cardID], tag)
+	}
+	return tags, rows.Err()
+}
+
+func replaceCardTags(q querier, cardID int, tags []string) error {
+	if _, err := q.Exec("DELETE FROM card_tag WHERE card_id = ?", cardID); err != nil {
+		log.Printf("Error clearing tags of card %d: %v", cardID, err)
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := q.Exec(sqlDialect.ignore("INSERT INTO card_tag (card_id, tag) VALUES (?, ?)"), cardID, tag); err != nil {
+			log.Printf("Error tagging card %d: %v", cardID, err)
+			return err
+		}
+	}
+	return nil
+}
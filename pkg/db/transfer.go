@@ -0,0 +1,102 @@
+package db
+
+import (
+	"log"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// MoveCards moves cards to deckID in one transaction. The user's review state
+// is kept unless resetState is set; review history is always kept.
+func MoveCards(userID int, cardIDs []int, deckID int, resetState bool) ([]types.CardTransfer, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	moved := make([]types.CardTransfer, 0, len(cardIDs))
+	for _, cardID := range cardIDs {
+		if err := moveCard(tx, cardID, deckID); err != nil {
+			return nil, err
+		}
+		if resetState {
+			if _, err := tx.Exec("DELETE FROM card_state WHERE user_id = ? AND card_id = ?", userID, cardID); err != nil {
+				log.Printf("Error resetting state of card %d: %v", cardID, err)
+				return nil, err
+			}
+		}
+		moved = append(moved, types.CardTransfer{CardID: cardID, DeckID: deckID})
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing card move: %v", err)
+		return nil, err
+	}
+	return moved, nil
+}
+
+// CopyCards copies cards with their tags into deckID in one transaction. The
+// user's review state is copied too unless resetState is set.
+func CopyCards(userID int, cardIDs []int, deckID int, resetState bool) ([]types.CardTransfer, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	copied := make([]types.CardTransfer, 0, len(cardIDs))
+	for _, cardID := range cardIDs {
+		var question, answer string
+		if err := tx.QueryRow("SELECT question, answer FROM card WHERE id = ?", cardID).Scan(&question, &answer); err != nil {
+			log.Printf("Error retrieving card %d: %v", cardID, err)
+			return nil, err
+		}
+		tags, err := loadTagsOfCard(tx, cardID)
+		if err != nil {
+			return nil, err
+		}
+		newID, err := insertCard(tx, deckID, question, answer, tags)
+		if err != nil {
+			return nil, err
+		}
+		if !resetState {
+			_, err := tx.Exec(`INSERT INTO card_state (user_id, card_id, due_at, interval_days, ease, reps, lapses, last_reviewed_at)
+				SELECT user_id, ?, due_at, interval_days, ease, reps, lapses, last_reviewed_at
+				FROM card_state WHERE user_id = ? AND card_id = ?`, newID, userID, cardID)
+			if err != nil {
+				log.Printf("Error copying state of card %d: %v", cardID, err)
+				return nil, err
+			}
+		}
+		copied = append(copied, types.CardTransfer{CardID: cardID, NewCardID: int(newID), DeckID: deckID})
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing card copy: %v", err)
+		return nil, err
+	}
+	return copied, nil
+}
+
+func loadTagsOfCard(q querier, cardID int) ([]string, error) {
+	rows, err := q.Query("SELECT tag FROM card_tag WHERE card_id = ? ORDER BY tag", cardID)
+	if err != nil {
+		log.Printf("Error retrieving tags of card %d: %v", cardID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			log.Printf("Error scanning tag row: %v", err)
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
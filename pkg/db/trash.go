@@ -0,0 +1,202 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// trashCard moves a card to the trash. Its tags, review state and upstream
+// link are kept so a restore brings it back unchanged.
+func trashCard(q querier, cardID int) (int64, error) {
+	result, err := q.Exec("UPDATE card SET deleted_at = "+sqlDialect.now+" WHERE id = ? AND deleted_at IS NULL", cardID)
+	if err != nil {
+		log.Printf("Error deleting card with id %d: %v\n", cardID, err)
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error retrieving rows: %v", err)
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// GetTrash lists the user's trashed decks and the trashed cards of live decks
+// they can edit. retentionDays sets the purge time reported for each item.
+func GetTrash(userID, retentionDays int) (*types.Trash, error) {
+	trash := &types.Trash{Decks: []types.TrashedDeck{}, Cards: []types.TrashedCard{}}
+
+	rows, err := DB.Query(`SELECT d.id, d.name, d.deleted_at,
+			(SELECT COUNT(*) FROM card c WHERE c.deck_id = d.id AND c.deleted_at IS NULL)
+		FROM deck d
+		WHERE d.user_id = ? AND d.deleted_at IS NOT NULL
+		ORDER BY d.deleted_at DESC, d.id DESC`, userID)
+	if err != nil {
+		log.Printf("Error retrieving trashed decks: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var deck types.TrashedDeck
+		if err := rows.Scan(&deck.ID, &deck.Name, &deck.DeletedAt, &deck.CardCount); err != nil {
+			log.Printf("Error scanning trashed deck row: %v", err)
+			return nil, err
+		}
+		deck.PurgeAt = purgeTime(deck.DeletedAt, retentionDays)
+		trash.Decks = append(trash.Decks, deck)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = DB.Query(`SELECT c.id, c.deck_id, d.name, c.question, c.answer, c.deleted_at
+		FROM card c JOIN deck d ON d.id = c.deck_id
+		LEFT JOIN deck_share s ON s.deck_id = d.id AND s.user_id = ?
+		WHERE c.deleted_at IS NOT NULL AND d.deleted_at IS NULL AND (d.user_id = ? OR s.role = ?)
+		ORDER BY c.deleted_at DESC, c.id DESC`, userID, userID, types.RoleEditor)
+	if err != nil {
+		log.Printf("Error retrieving trashed cards: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var card types.TrashedCard
+		if err := rows.Scan(&card.ID, &card.DeckID, &card.DeckName, &card.Question, &card.Answer, &card.DeletedAt); err != nil {
+			log.Printf("Error scanning trashed card row: %v", err)
+			return nil, err
+		}
+		card.PurgeAt = purgeTime(card.DeletedAt, retentionDays)
+		trash.Cards = append(trash.Cards, card)
+	}
+	return trash, rows.Err()
+}
+
+func purgeTime(deletedAt string, retentionDays int) string {
+	t, err := time.Parse(time.DateTime, deletedAt)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, retentionDays).Format(time.DateTime)
+}
+
+// GetTrashedDeckOwner returns the owner of a trashed deck, or 0 when the deck
+// does not exist or is not in the trash.
+func GetTrashedDeckOwner(deckID int) (int, error) {
+	var ownerID int
+	err := DB.QueryRow("SELECT user_id FROM deck WHERE id = ? AND deleted_at IS NOT NULL", deckID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving deck %d: %v", deckID, err)
+		return 0, err
+	}
+	return ownerID, nil
+}
+
+// GetTrashedCardDeckID returns the deck of a trashed card, or 0 when the card
+// does not exist or is not in the trash.
+func GetTrashedCardDeckID(cardID int) (int, error) {
+	var deckID int
+	err := DB.QueryRow("SELECT deck_id FROM card WHERE id = ? AND deleted_at IS NOT NULL", cardID).Scan(&deckID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving card %d: %v", cardID, err)
+		return 0, err
+	}
+	return deckID, nil
+}
+
+func RestoreDeck(deckID int) (int64, error) {
+	return restore("deck", deckID)
+}
+
+func RestoreCard(cardID int) (int64, error) {
+	return restore("card", cardID)
+}
+
+func restore(table string, id int) (int64, error) {
+	result, err := DB.Exec("UPDATE "+table+" SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		log.Printf("Error restoring %s %d: %v", table, id, err)
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error retrieving rows: %v", err)
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// PurgeTrash permanently removes decks and cards trashed more than
+// retentionDays ago, along with the cards of purged decks.
+func PurgeTrash(retentionDays int) (decks, cards int, err error) {
+	deckIDs, err := expiredIDs("deck", retentionDays)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, deckID := range deckIDs {
+		if err := PurgeDeck(deckID); err != nil {
+			return decks, cards, err
+		}
+		decks++
+	}
+
+	cardIDs, err := expiredIDs("card", retentionDays)
+	if err != nil {
+		return decks, cards, err
+	}
+	for _, cardID := range cardIDs {
+		if _, err := deleteCard(DB, cardID); err != nil {
+			return decks, cards, err
+		}
+		cards++
+	}
+	return decks, cards, nil
+}
+
+func expiredIDs(table string, retentionDays int) ([]int, error) {
+	rows, err := DB.Query("SELECT id FROM "+table+" WHERE deleted_at < "+sqlDialect.daysAgo("?"), retentionDays)
+	if err != nil {
+		log.Printf("Error retrieving expired %s rows: %v", table, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("Error scanning %s id: %v", table, err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// PurgeDeck permanently removes a deck and everything belonging to it in one
+// transaction.
+func PurgeDeck(deckID int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteDeck(tx, deckID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing purge of deck %d: %v", deckID, err)
+		return err
+	}
+	return nil
+}
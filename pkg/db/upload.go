@@ -0,0 +1,45 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+)
+
+// SaveUpload stores an uploaded file until the user confirms its import, and
+// drops uploads abandoned for more than a day.
+func SaveUpload(id string, userID int, filename string, content []byte) error {
+	if _, err := DB.Exec("DELETE FROM import_upload WHERE created_at < " + sqlDialect.daysAgo("1")); err != nil {
+		log.Printf("Error removing expired uploads: %v", err)
+	}
+	_, err := DB.Exec("INSERT INTO import_upload (id, user_id, filename, content, created_at) VALUES (?, ?, ?, ?, "+sqlDialect.now+")",
+		id, userID, filename, content)
+	if err != nil {
+		log.Printf("Error saving upload %s: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// GetUpload returns a user's stored upload, or nil content when it does not
+// exist or belongs to someone else.
+func GetUpload(id string, userID int) (string, []byte, error) {
+	var filename string
+	var content []byte
+	err := DB.QueryRow("SELECT filename, content FROM import_upload WHERE id = ? AND user_id = ?", id, userID).Scan(&filename, &content)
+	if err == sql.ErrNoRows {
+		return "", nil, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving upload %s: %v", id, err)
+		return "", nil, err
+	}
+	return filename, content, nil
+}
+
+func DeleteUpload(id string) error {
+	if _, err := DB.Exec("DELETE FROM import_upload WHERE id = ?", id); err != nil {
+		log.Printf("Error deleting upload %s: %v", id, err)
+		return err
+	}
+	return nil
+}
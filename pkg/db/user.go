@@ -0,0 +1,34 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"go-flashcards-server/pkg/types"
+)
+
+func CreateUser(user types.User) (int64, error) {
+	userID, err := insertID(DB, "INSERT INTO user (first_name, last_name, email, password_hash, salt) VALUES (?, ?, ?, ?, ?)",
+		user.FirstName, user.LastName, user.Email, user.PasswordHash, user.Salt)
+	if err != nil {
+		log.Printf("Error creating user: %v", err)
+		return 0, err
+	}
+	return userID, nil
+}
+
+// GetUserByEmail returns the account registered with email, or nil when there
+// is none.
+func GetUserByEmail(email string) (*types.User, error) {
+	var user types.User
+	err := DB.QueryRow("SELECT id, first_name, last_name, email, password_hash, salt FROM user WHERE email = ?", email).
+		Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.PasswordHash, &user.Salt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving user with email %s: %v", email, err)
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,306 @@
+// Package dedupe finds cards whose questions are the same or nearly the same.
+// Questions are compared after normalization; near duplicates are those whose
+// edit-distance similarity reaches a threshold.
+package dedupe
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// MaxFuzzyCards bounds how many cards near-duplicate matching runs over,
+// since grouping compares every pair of them.
+const MaxFuzzyCards = 500
+
+var ErrTooManyCards = errors.New("too many cards for near-duplicate matching")
+
+// Normalize lowercases text and reduces it to words separated by single
+// spaces, so punctuation, markup and spacing differences do not matter.
+func Normalize(text string) string {
+	text = strings.ToLower(text)
+	return strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// Similarity is 1 minus the edit distance between a and b divided by the
+// length of the longer one.
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// myers is the edit distance between a pattern of m runes, given by the
+// positions of each of its runes in 64-bit blocks, and text. It computes the
+// same distance as levenshtein 64 pattern runes at a time (Myers 1999, in
+// Hyyrö's block form).
+func myers(peq map[rune][]uint64, m int, text []rune) int {
+	if m == 0 {
+		return len(text)
+	}
+	blocks := (m + 63) / 64
+	pv := make([]uint64, blocks)
+	mv := make([]uint64, blocks)
+	for i := range pv {
+		pv[i] = ^uint64(0)
+	}
+	last := uint64(1) << ((m - 1) % 64)
+	score := m
+	for _, c := range text {
+		eqs := peq[c]
+		// carry is the change in distance along the row coming out of the
+		// previous block; the first row grows by one per text rune.
+		carry := 1
+		for w := range blocks {
+			var eq uint64
+			if eqs != nil {
+				eq = eqs[w]
+			}
+			xv := eq | mv[w]
+			if carry < 0 {
+				eq |= 1
+			}
+			xh := (((eq & pv[w]) + pv[w]) ^ pv[w]) | eq
+			ph := mv[w] | ^(xh | pv[w])
+			mh := pv[w] & xh
+			high := uint64(1) << 63
+			if w == blocks-1 {
+				high = last
+			}
+			out := 0
+			if ph&high != 0 {
+				out = 1
+			} else if mh&high != 0 {
+				out = -1
+			}
+			ph <<= 1
+			mh <<= 1
+			if carry < 0 {
+				mh |= 1
+			} else if carry > 0 {
+				ph |= 1
+			}
+			pv[w] = mh | ^(xv | ph)
+			mv[w] = ph & xv
+			carry = out
+		}
+		score += carry
+	}
+	return score
+}
+
+type entry struct {
+	card       types.DuplicateCard
+	normalized string
+	runes      []rune
+	// peq holds the positions of each rune for myers.
+	peq map[rune][]uint64
+}
+
+func newEntry(card types.DuplicateCard) entry {
+	e := entry{card: card, normalized: Normalize(card.Question)}
+	e.runes = []rune(e.normalized)
+	e.peq = map[rune][]uint64{}
+	for i, r := range e.runes {
+		if e.peq[r] == nil {
+			e.peq[r] = make([]uint64, (len(e.runes)+63)/64)
+		}
+		e.peq[r][i/64] |= 1 << (i % 64)
+	}
+	return e
+}
+
+// Index holds cards to check new questions against. A threshold of 0 or 1
+// only matches identical normalized questions.
+type Index struct {
+	threshold float64
+	exact     map[string][]int
+	entries   []entry
+}
+
+func NewIndex(threshold float64) *Index {
+	return &Index{threshold: threshold, exact: map[string][]int{}}
+}
+
+func (ix *Index) Add(card types.DuplicateCard) {
+	e := newEntry(card)
+	ix.exact[e.normalized] = append(ix.exact[e.normalized], len(ix.entries))
+	ix.entries = append(ix.entries, e)
+}
+
+func (ix *Index) Len() int {
+	return len(ix.entries)
+}
+
+func (ix *Index) fuzzy() bool {
+	return ix.threshold > 0 && ix.threshold < 1
+}
+
+// CheckFuzzy returns ErrTooManyCards when near-duplicate matching would run
+// over more than MaxFuzzyCards cards, counting extra cards still to be added.
+func (ix *Index) CheckFuzzy(extra int) error {
+	if ix.fuzzy() && len(ix.entries)+extra > MaxFuzzyCards {
+		return ErrTooManyCards
+	}
+	return nil
+}
+
+// Find returns the indexed cards duplicating question, most similar first.
+func (ix *Index) Find(question string) []types.DuplicateMatch {
+	query := newEntry(types.DuplicateCard{Question: question})
+	matches := []types.DuplicateMatch{}
+	for _, i := range ix.exact[query.normalized] {
+		matches = append(matches, types.DuplicateMatch{DuplicateCard: ix.entries[i].card, Similarity: 1, Exact: true})
+	}
+	if !ix.fuzzy() {
+		return matches
+	}
+
+	for i := range ix.entries {
+		e := &ix.entries[i]
+		if e.normalized == query.normalized {
+			continue
+		}
+		if similarity, ok := ix.similar(&query, e); ok {
+			matches = append(matches, types.DuplicateMatch{DuplicateCard: e.card, Similarity: similarity})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	return matches
+}
+
+// mayMatch rules out pairs whose length difference alone keeps them below the
+// threshold.
+func (ix *Index) mayMatch(a, b int) bool {
+	longest := max(a, b)
+	if longest == 0 {
+		return true
+	}
+	return 1-float64(abs(a-b))/float64(longest) >= ix.threshold
+}
+
+// similar returns the similarity of a and b when it reaches the threshold.
+func (ix *Index) similar(a, b *entry) (float64, bool) {
+	if !ix.mayMatch(len(a.runes), len(b.runes)) {
+		return 0, false
+	}
+	longest := max(len(a.runes), len(b.runes))
+	if longest == 0 {
+		return 1, true
+	}
+	similarity := 1 - float64(myers(a.peq, len(a.runes), b.runes))/float64(longest)
+	return similarity, similarity >= ix.threshold
+}
+
+// Groups clusters the indexed cards into groups of duplicates. Only groups
+// containing a card accepted by include are returned. Callers check
+// CheckFuzzy first, as near duplicates are found by comparing pairs of cards
+// of similar length.
+func (ix *Index) Groups(include func(types.DuplicateCard) bool) []types.DuplicateGroup {
+	parent := make([]int, len(ix.entries))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		if ri, rj := find(i), find(j); ri != rj {
+			parent[rj] = ri
+		}
+	}
+
+	for _, indexes := range ix.exact {
+		for _, j := range indexes[1:] {
+			union(indexes[0], j)
+		}
+	}
+	if ix.fuzzy() {
+		// Sorted by length, each card is compared only with the following
+		// cards until they are too long to match it.
+		order := make([]int, len(ix.entries))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool { return len(ix.entries[order[i]].runes) < len(ix.entries[order[j]].runes) })
+		for n, i := range order {
+			a := &ix.entries[i]
+			for _, j := range order[n+1:] {
+				b := &ix.entries[j]
+				if !ix.mayMatch(len(a.runes), len(b.runes)) {
+					break
+				}
+				if a.normalized == b.normalized {
+					continue
+				}
+				if _, ok := ix.similar(a, b); ok {
+					union(i, j)
+				}
+			}
+		}
+	}
+
+	members := map[int][]int{}
+	for i := range ix.entries {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+	groups := []types.DuplicateGroup{}
+	for _, indexes := range members {
+		if len(indexes) < 2 {
+			continue
+		}
+		group := types.DuplicateGroup{Exact: true}
+		relevant := false
+		for _, i := range indexes {
+			card := ix.entries[i].card
+			group.Cards = append(group.Cards, card)
+			relevant = relevant || include(card)
+			group.Exact = group.Exact && ix.entries[i].normalized == ix.entries[indexes[0]].normalized
+		}
+		if relevant {
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Cards[0].ID < groups[j].Cards[0].ID })
+	return groups
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
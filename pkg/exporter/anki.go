@@ -0,0 +1,313 @@
+package exporter
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-flashcards-server/pkg/types"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	ankiSchemaVersion = 11
+	ankiDefaultConfID = 1
+	ankiDayLayout     = time.DateTime
+)
+
+var ankiTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// ankiSchema is the legacy (schema 11) collection layout, which every Anki
+// version can import.
+var ankiSchema = []string{
+	`CREATE TABLE col (
+		id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+		ver integer not null, dty integer not null, usn integer not null, ls integer not null,
+		conf text not null, models text not null, decks text not null, dconf text not null, tags text not null
+	)`,
+	`CREATE TABLE notes (
+		id integer primary key, guid text not null, mid integer not null, mod integer not null,
+		usn integer not null, tags text not null, flds text not null, sfld integer not null,
+		csum integer not null, flags integer not null, data text not null
+	)`,
+	`CREATE TABLE cards (
+		id integer primary key, nid integer not null, did integer not null, ord integer not null,
+		mod integer not null, usn integer not null, type integer not null, queue integer not null,
+		due integer not null, ivl integer not null, factor integer not null, reps integer not null,
+		lapses integer not null, left integer not null, odue integer not null, odid integer not null,
+		flags integer not null, data text not null
+	)`,
+	`CREATE TABLE revlog (
+		id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+		ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null,
+		type integer not null
+	)`,
+	`CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null)`,
+	`CREATE INDEX ix_notes_usn ON notes (usn)`,
+	`CREATE INDEX ix_cards_usn ON cards (usn)`,
+	`CREATE INDEX ix_revlog_usn ON revlog (usn)`,
+	`CREATE INDEX ix_cards_nid ON cards (nid)`,
+	`CREATE INDEX ix_cards_sched ON cards (did, queue, due)`,
+	`CREATE INDEX ix_revlog_cid ON revlog (cid)`,
+	`CREATE INDEX ix_notes_csum ON notes (csum)`,
+}
+
+// WriteAnkiPackage writes the deck as an .apkg file with a Basic note per card.
+// Review state is included when withState is set; other cards are new.
+func WriteAnkiPackage(w io.Writer, deck *types.ExportDeck, withState bool, progress types.Progress) error {
+	file, err := os.CreateTemp("", "export-*.anki2")
+	if err != nil {
+		return err
+	}
+	path := file.Name()
+	file.Close()
+	defer os.Remove(path)
+
+	if err := buildAnkiCollection(path, deck, withState, progress); err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	entry, err := archive.Create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	collection, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer collection.Close()
+	if _, err := io.Copy(entry, collection); err != nil {
+		return err
+	}
+	media, err := archive.Create("media")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(media, "{}"); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func buildAnkiCollection(path string, deck *types.ExportDeck, withState bool, progress types.Progress) error {
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range ankiSchema {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("creating collection: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	nowMillis := now.UnixMilli()
+	created := collectionCreated(deck, now, withState)
+	deckID := nowMillis
+	modelID := nowMillis + 1
+
+	models, decks, dconf, conf, err := ankiCollectionConfig(deck.Name, deckID, modelID, now.Unix())
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO col (id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags)
+		VALUES (1, ?, ?, ?, ?, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		created.Unix(), nowMillis, nowMillis, ankiSchemaVersion, conf, models, decks, dconf)
+	if err != nil {
+		return fmt.Errorf("writing collection: %w", err)
+	}
+
+	for i, card := range deck.Cards {
+		if progress != nil {
+			progress(i, len(deck.Cards))
+		}
+		noteID := nowMillis + int64(i)
+		guid, err := ankiGUID()
+		if err != nil {
+			return err
+		}
+		tags := ""
+		if len(card.Tags) > 0 {
+			tags = " " + strings.Join(card.Tags, " ") + " "
+		}
+		question := ankiField(card.Question)
+		sortField := ankiSortField(question)
+		_, err = tx.Exec(`INSERT INTO notes (id, guid, mid, mod, usn, tags, flds, sfld, csum, flags, data)
+			VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			noteID, guid, modelID, now.Unix(), tags, question+"\x1f"+ankiField(card.Answer), sortField, ankiChecksum(sortField))
+		if err != nil {
+			return fmt.Errorf("writing note: %w", err)
+		}
+
+		cardType, queue, due, interval, factor, reps, lapses := 0, 0, int64(i), 0, 0, 0, 0
+		if withState && card.State != nil && card.State.Reps > 0 {
+			dueAt, err := time.Parse(ankiDayLayout, card.State.DueAt)
+			if err == nil {
+				cardType, queue = 2, 2
+				due = int64(dueAt.Sub(created).Hours() / 24)
+				interval = max(card.State.IntervalDays, 1)
+				factor = int(card.State.Ease * 1000)
+				reps, lapses = card.State.Reps, card.State.Lapses
+			}
+		}
+		_, err = tx.Exec(`INSERT INTO cards (id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data)
+			VALUES (?, ?, ?, 0, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, '')`,
+			noteID, noteID, deckID, now.Unix(), cardType, queue, due, interval, factor, reps, lapses)
+		if err != nil {
+			return fmt.Errorf("writing card: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// collectionCreated returns the collection creation day that review due dates
+// are counted from. It is moved back so no exported card is due before it.
+func collectionCreated(deck *types.ExportDeck, now time.Time, withState bool) time.Time {
+	created := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !withState {
+		return created
+	}
+	for _, card := range deck.Cards {
+		if card.State == nil {
+			continue
+		}
+		if dueAt, err := time.Parse(ankiDayLayout, card.State.DueAt); err == nil && dueAt.Before(created) {
+			created = time.Date(dueAt.Year(), dueAt.Month(), dueAt.Day(), 0, 0, 0, 0, time.UTC)
+		}
+	}
+	return created
+}
+
+func ankiCollectionConfig(deckName string, deckID, modelID, mod int64) (models, decks, dconf, conf string, err error) {
+	model := map[string]any{
+		"id":    modelID,
+		"name":  "Basic (flashcards export)",
+		"type":  0,
+		"mod":   mod,
+		"usn":   -1,
+		"sortf": 0,
+		"did":   deckID,
+		"tmpls": []map[string]any{{
+			"name":  "Card 1",
+			"ord":   0,
+			"qfmt":  "{{Front}}",
+			"afmt":  "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}",
+			"did":   nil,
+			"bqfmt": "",
+			"bafmt": "",
+		}},
+		"flds": []map[string]any{
+			{"name": "Front", "ord": 0, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []any{}},
+			{"name": "Back", "ord": 1, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []any{}},
+		},
+		"css":       ".card {\n font-family: arial;\n font-size: 20px;\n text-align: center;\n}\n",
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"req":       []any{[]any{0, "any", []int{0}}},
+		"tags":      []string{},
+		"vers":      []any{},
+	}
+	newDeck := func(id int64, name string) map[string]any {
+		return map[string]any{
+			"id":        id,
+			"name":      name,
+			"mod":       mod,
+			"usn":       -1,
+			"lrnToday":  []int{0, 0},
+			"revToday":  []int{0, 0},
+			"newToday":  []int{0, 0},
+			"timeToday": []int{0, 0},
+			"collapsed": false,
+			"desc":      "",
+			"dyn":       0,
+			"conf":      ankiDefaultConfID,
+			"extendNew": 10,
+			"extendRev": 50,
+		}
+	}
+	deckConf := map[string]any{
+		"id":       ankiDefaultConfID,
+		"name":     "Default",
+		"mod":      0,
+		"usn":      0,
+		"maxTaken": 60,
+		"autoplay": true,
+		"timer":    0,
+		"replayq":  true,
+		"dyn":      false,
+		"new": map[string]any{
+			"delays": []float64{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500,
+			"order": 1, "perDay": 20, "bury": true, "separate": true,
+		},
+		"rev": map[string]any{
+			"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "maxIvl": 36500, "bury": true,
+			"minSpace": 1, "ivlFct": 1, "hardFactor": 1.2,
+		},
+		"lapse": map[string]any{
+			"delays": []float64{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0,
+		},
+	}
+
+	values := []any{
+		map[string]any{fmt.Sprint(modelID): model},
+		map[string]any{"1": newDeck(1, "Default"), fmt.Sprint(deckID): newDeck(deckID, deckName)},
+		map[string]any{fmt.Sprint(ankiDefaultConfID): deckConf},
+		map[string]any{"curDeck": deckID, "curModel": modelID, "nextPos": 1, "sortType": "noteFld", "sortBackwards": false},
+	}
+	encoded := make([]string, len(values))
+	for i, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		encoded[i] = string(data)
+	}
+	return encoded[0], encoded[1], encoded[2], encoded[3], nil
+}
+
+// ankiField turns plain card text into an Anki field, which Anki reads as
+// HTML.
+func ankiField(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// ankiSortField is a field with its HTML stripped, as Anki sorts and
+// checksums it.
+func ankiSortField(field string) string {
+	return html.UnescapeString(ankiTagPattern.ReplaceAllString(field, ""))
+}
+
+// ankiChecksum is Anki's duplicate-detection checksum: the first 8 hex digits
+// of the SHA-1 of the sort field.
+func ankiChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+func ankiGUID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
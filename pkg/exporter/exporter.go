@@ -0,0 +1,125 @@
+// Package exporter writes decks in formats other applications can read.
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go-flashcards-server/pkg/types"
+)
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Filename returns a download name for a deck export.
+func Filename(deckName, format string) string {
+	name := strings.Trim(unsafeFilenameChars.ReplaceAllString(deckName, "-"), "-.")
+	if name == "" {
+		name = "deck"
+	}
+	return name + "." + format
+}
+
+// ContentType returns the MIME type of an export format, or false when the
+// format is unknown.
+func ContentType(format string) (string, bool) {
+	switch format {
+	case "csv":
+		return "text/csv; charset=utf-8", true
+	case "json":
+		return "application/json", true
+	case "apkg":
+		return "application/octet-stream", true
+	case "md":
+		return "text/markdown; charset=utf-8", true
+	}
+	return "", false
+}
+
+// Write writes the deck in the given format. Each writer tells progress how
+// many cards are written before each one.
+func Write(w io.Writer, format string, deck *types.ExportDeck, withState bool, progress types.Progress) error {
+	switch format {
+	case "csv":
+		return WriteCSV(w, deck, withState, progress)
+	case "json":
+		return WriteJSON(w, deck, progress)
+	case "apkg":
+		return WriteAnkiPackage(w, deck, withState, progress)
+	case "md":
+		return WriteMarkdown(w, deck, progress)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// WriteCSV writes one row per card after a header row. Scheduling columns are
+// added when withState is set and left empty for cards never reviewed.
+func WriteCSV(w io.Writer, deck *types.ExportDeck, withState bool, progress types.Progress) error {
+	writer := csv.NewWriter(w)
+	header := []string{"question", "answer", "tags"}
+	if withState {
+		header = append(header, "due_at", "interval_days", "ease", "reps", "lapses")
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for i, card := range deck.Cards {
+		if progress != nil {
+			progress(i, len(deck.Cards))
+		}
+		record := []string{card.Question, card.Answer, strings.Join(card.Tags, " ")}
+		if withState {
+			if s := card.State; s != nil {
+				record = append(record, s.DueAt, strconv.Itoa(s.IntervalDays),
+					strconv.FormatFloat(s.Ease, 'f', -1, 64), strconv.Itoa(s.Reps), strconv.Itoa(s.Lapses))
+			} else {
+				record = append(record, "", "", "", "", "")
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the deck as a JSON document, encoding cards one at a time.
+func WriteJSON(w io.Writer, deck *types.ExportDeck, progress types.Progress) error {
+	header, err := json.Marshal(struct {
+		ID        int    `json:"id"`
+		Name      string `json:"name"`
+		CreatedAt string `json:"created_at"`
+	}{deck.ID, deck.Name, deck.CreatedAt})
+	if err != nil {
+		return err
+	}
+	// Reopen the object to append the cards array.
+	if _, err := io.WriteString(w, string(header[:len(header)-1])+`,"cards":[`); err != nil {
+		return err
+	}
+	for i, card := range deck.Cards {
+		if progress != nil {
+			progress(i, len(deck.Cards))
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(card)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
@@ -0,0 +1,107 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// WriteMarkdown writes the deck in the Markdown format read by
+// importer.ReadMarkdown. Tags shared by every card go in the front matter and
+// each card carries its ID so an edited file can be imported back.
+func WriteMarkdown(w io.Writer, deck *types.ExportDeck, progress types.Progress) error {
+	out := bufio.NewWriter(w)
+	common := commonTags(deck.Cards)
+
+	fmt.Fprintf(out, "---\nid: %d\n", deck.ID)
+	if len(common) > 0 {
+		fmt.Fprintf(out, "tags: %s\n", markdownTags(common))
+	}
+	fmt.Fprintf(out, "---\n\n# %s\n", singleLine(deck.Name))
+
+	for i, card := range deck.Cards {
+		if progress != nil {
+			progress(i, len(deck.Cards))
+		}
+		var extra []string
+		for _, tag := range card.Tags {
+			if !slices.Contains(common, tag) {
+				extra = append(extra, tag)
+			}
+		}
+		if len(extra) > 0 {
+			fmt.Fprintf(out, "\n<!-- card: %d, tags: %s -->\n", card.ID, markdownTags(extra))
+		} else {
+			fmt.Fprintf(out, "\n<!-- card: %d -->\n", card.ID)
+		}
+
+		question, answer := card.Question, card.Answer
+		if isTermLine(question, answer) {
+			fmt.Fprintf(out, "%s :: %s\n", question, answer)
+			continue
+		}
+		fmt.Fprintf(out, "Q: %s\nA: %s\n", indentBlock(question), indentBlock(answer))
+	}
+	return out.Flush()
+}
+
+// commonTags returns the tags present on every card.
+func commonTags(cards []types.ExportCard) []string {
+	if len(cards) == 0 {
+		return nil
+	}
+	common := slices.Clone(cards[0].Tags)
+	for _, card := range cards[1:] {
+		common = slices.DeleteFunc(common, func(tag string) bool {
+			return !slices.Contains(card.Tags, tag)
+		})
+	}
+	return common
+}
+
+// isTermLine reports whether a card can be written as "term :: definition"
+// and read back unchanged. Term lines are trimmed when read.
+func isTermLine(question, answer string) bool {
+	if strings.ContainsAny(question+answer, "\r\n") || strings.Contains(question, "::") {
+		return false
+	}
+	if question != strings.TrimSpace(question) || answer != strings.TrimSpace(answer) {
+		return false
+	}
+	for _, prefix := range []string{"#", "<!--", "Q:", "A:", "---"} {
+		if strings.HasPrefix(question, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// indentBlock indents continuation lines so they stay inside a Q: or A: block.
+// Empty lines are indented too, so trailing ones are kept.
+func indentBlock(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 1; i < len(lines); i++ {
+		lines[i] = "  " + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownTags joins tags with commas, quoting those that contain one.
+func markdownTags(tags []string) string {
+	quoted := make([]string, len(tags))
+	for i, tag := range tags {
+		if strings.ContainsAny(tag, `,"`) {
+			tag = `"` + strings.ReplaceAll(tag, `"`, `""`) + `"`
+		}
+		quoted[i] = tag
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
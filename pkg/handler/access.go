@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"log"
+	"net/http"
+)
+
+// requireDeckRole checks that the caller holds at least the required role on
+// the deck. It writes the error response and returns false when they do not.
+func requireDeckRole(w http.ResponseWriter, r *http.Request, deckID int, required string) (int, bool) {
+	return checkDeckRole(w, r, deckID, required, "Deck not found")
+}
+
+// requireCardRole checks the caller's role on the deck containing the card.
+func requireCardRole(w http.ResponseWriter, r *http.Request, cardID int, required string) (int, bool) {
+	deckID, err := store.GetCardDeckID(cardID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error checking card access", http.StatusInternalServerError)
+		return 0, false
+	}
+	if deckID == 0 {
+		utils.HandleErrorResponse(w, "Card not found", http.StatusNotFound)
+		return 0, false
+	}
+	return checkDeckRole(w, r, deckID, required, "Card not found")
+}
+
+func checkDeckRole(w http.ResponseWriter, r *http.Request, deckID int, required, notFound string) (int, bool) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+
+	role, err := store.GetDeckRole(deckID, userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error checking deck access", http.StatusInternalServerError)
+		return 0, false
+	}
+	if role == "" {
+		utils.HandleErrorResponse(w, notFound, http.StatusNotFound)
+		return 0, false
+	}
+	if !types.RoleAllows(role, required) {
+		utils.HandleErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go-flashcards-server/pkg/types"
+
+	"github.com/gorilla/mux"
+)
+
+// expectNotFound checks that a request is refused with 404 and the given
+// message, so an inaccessible deck looks the same as a missing one.
+func (c *testClient) expectNotFound(method, path string, body any, message string) {
+	c.t.Helper()
+	c.expect(method, path, body, http.StatusNotFound)
+	if c.message != message {
+		c.t.Errorf("%s %s: message %q, want %q", method, path, c.message, message)
+	}
+}
+
+func TestOtherUsersDecksAreHidden(t *testing.T) {
+	server, _ := newTestServer(t)
+	ana := signUp(t, server, "ana@example.com")
+	ben := signUp(t, server, "ben@example.com")
+	deckID := ana.createDeck("Spanish")
+	cardID := ana.createCard(deckID, "hola", "hello")
+	deck, card := strconv.Itoa(deckID), strconv.Itoa(cardID)
+
+	// Every route naming Ana's deck or card answers as if it did not exist.
+	ids := strings.NewReplacer("{deck_id}", deck, "{card_id}", card, "{user_id}", strconv.Itoa(ben.userID), "{rev}", "1")
+	routes := 0
+	err := server.(*mux.Router).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil || !strings.Contains(template, "{deck_id}") && !strings.Contains(template, "{card_id}") {
+			return nil
+		}
+		message := "Deck not found"
+		if strings.Contains(template, "{card_id}") {
+			message = "Card not found"
+		}
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			ben.expectNotFound(method, ids.Replace(template), nil, message)
+			routes++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if routes < 19 {
+		t.Errorf("checked %d routes, want every deck and card route", routes)
+	}
+	ben.expectNotFound("POST", "/card/create", Card{DeckID: deckID, Question: "q", Answer: "a"}, "Deck not found")
+
+	var decks []DeckPayload
+	ben.do("GET", "/deck", nil, &decks)
+	if len(decks) != 0 {
+		t.Errorf("ben's decks = %+v, want none", decks)
+	}
+
+	// Nothing Ben tried changed Ana's deck.
+	var cards []Card
+	ana.do("GET", "/card/"+deck, nil, &cards)
+	if len(cards) != 1 || cards[0].Question != "hola" {
+		t.Errorf("ana's cards = %+v, want hola only", cards)
+	}
+}
+
+func TestSharedViewer(t *testing.T) {
+	server, _ := newTestServer(t)
+	ana := signUp(t, server, "ana@example.com")
+	ben := signUp(t, server, "ben@example.com")
+	deckID := ana.createDeck("Spanish")
+	cardID := ana.createCard(deckID, "hola", "hello")
+	deck, card := strconv.Itoa(deckID), strconv.Itoa(cardID)
+	ana.expect("POST", "/deck/share/"+deck, map[string]string{"email": "ben@example.com", "role": types.RoleViewer}, http.StatusOK)
+
+	ben.expect("GET", "/card/"+deck, nil, http.StatusOK)
+	ben.expect("PUT", "/deck/update/"+deck, DeckPayload{Name: "Mine"}, http.StatusForbidden)
+	ben.expect("DELETE", "/deck/delete/"+deck, nil, http.StatusForbidden)
+	ben.expect("POST", "/card/create", Card{DeckID: deckID, Question: "q", Answer: "a"}, http.StatusForbidden)
+	ben.expect("PUT", "/card/update/"+card, Card{Question: "q", Answer: "a"}, http.StatusForbidden)
+	ben.expect("DELETE", "/card/delete/"+card, nil, http.StatusForbidden)
+	ben.expect("GET", "/deck/share/"+deck, nil, http.StatusForbidden)
+	ben.expect("POST", "/deck/share/"+deck, map[string]string{"email": "ben@example.com", "role": types.RoleEditor}, http.StatusForbidden)
+
+	var decks []DeckPayload
+	ben.do("GET", "/deck", nil, &decks)
+	if len(decks) != 1 || decks[0].ID != deckID {
+		t.Errorf("ben's decks = %+v, want the shared deck", decks)
+	}
+
+	ana.expect("DELETE", "/deck/share/"+deck+"/"+strconv.Itoa(ben.userID), nil, http.StatusOK)
+	ben.expectNotFound("GET", "/card/"+deck, nil, "Deck not found")
+	ben.expectNotFound("PUT", "/card/update/"+card, Card{Question: "q", Answer: "a"}, "Card not found")
+}
+
+func TestSharedEditor(t *testing.T) {
+	server, _ := newTestServer(t)
+	ana := signUp(t, server, "ana@example.com")
+	ben := signUp(t, server, "ben@example.com")
+	deckID := ana.createDeck("Spanish")
+	cardID := ana.createCard(deckID, "hola", "hello")
+	deck, card := strconv.Itoa(deckID), strconv.Itoa(cardID)
+	ana.expect("POST", "/deck/share/"+deck, map[string]string{"email": "ben@example.com", "role": types.RoleEditor}, http.StatusOK)
+
+	ben.expect("GET", "/card/"+deck, nil, http.StatusOK)
+	ben.expect("PUT", "/deck/update/"+deck, DeckPayload{Name: "Español"}, http.StatusOK)
+	ben.createCard(deckID, "gato", "cat")
+	ben.expect("PUT", "/card/update/"+card, Card{Question: "hola", Answer: "hi"}, http.StatusOK)
+	ben.expect("DELETE", "/card/delete/"+card, nil, http.StatusOK)
+	ben.expect("DELETE", "/deck/delete/"+deck, nil, http.StatusForbidden)
+	ben.expect("GET", "/deck/share/"+deck, nil, http.StatusForbidden)
+	ben.expect("DELETE", "/deck/share/"+deck+"/"+strconv.Itoa(ben.userID), nil, http.StatusForbidden)
+
+	var cards []Card
+	ana.do("GET", "/card/"+deck, nil, &cards)
+	if len(cards) != 1 || cards[0].Question != "gato" {
+		t.Errorf("ana's cards = %+v, want ben's gato only", cards)
+	}
+}
+
+func TestClassroomViewer(t *testing.T) {
+	server, memory := newTestServer(t)
+	ana := signUp(t, server, "ana@example.com")
+	ben := signUp(t, server, "ben@example.com")
+	deckID := ana.createDeck("Spanish")
+	cardID := ana.createCard(deckID, "hola", "hello")
+	deck, card := strconv.Itoa(deckID), strconv.Itoa(cardID)
+	memory.Assign(deckID, ben.userID)
+
+	ben.expect("GET", "/card/"+deck, nil, http.StatusOK)
+	ben.expect("PUT", "/deck/update/"+deck, DeckPayload{Name: "Mine"}, http.StatusForbidden)
+	ben.expect("POST", "/card/create", Card{DeckID: deckID, Question: "q", Answer: "a"}, http.StatusForbidden)
+	ben.expect("PUT", "/card/update/"+card, Card{Question: "q", Answer: "a"}, http.StatusForbidden)
+	ben.expect("DELETE", "/card/delete/"+card, nil, http.StatusForbidden)
+
+	// Assigned decks are studied through the classroom, not listed as Ben's.
+	var decks []DeckPayload
+	ben.do("GET", "/deck", nil, &decks)
+	if len(decks) != 0 {
+		t.Errorf("ben's decks = %+v, want none", decks)
+	}
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"log"
+	"net/http"
+)
+
+const maxBulkOperations = 1000
+
+type BulkRequest struct {
+	Operations []types.BulkOperation `json:"operations"`
+}
+
+// BulkCards applies create, update, delete and move operations in one
+// transaction. Every operation is checked first; if any is invalid nothing is
+// applied and the per-item results say why.
+func BulkCards(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if len(payload.Operations) == 0 {
+		utils.HandleErrorResponse(w, "At least one operation is required", http.StatusBadRequest)
+		return
+	}
+	if len(payload.Operations) > maxBulkOperations {
+		utils.HandleErrorResponse(w, fmt.Sprintf("At most %d operations are allowed", maxBulkOperations), http.StatusBadRequest)
+		return
+	}
+
+	checker := &bulkChecker{userID: userID, roles: map[int]string{}, cardDecks: map[int]int{}, deleted: map[int]bool{}}
+	results := make([]types.BulkResult, len(payload.Operations))
+	valid := true
+	for i := range payload.Operations {
+		op := &payload.Operations[i]
+		message, err := checker.check(op)
+		if err != nil {
+			utils.HandleErrorResponse(w, "Error checking card access", http.StatusInternalServerError)
+			return
+		}
+		results[i] = types.BulkResult{Index: i, Op: op.Op, OK: message == "", CardID: op.CardID, Error: message}
+		valid = valid && message == ""
+	}
+
+	status := http.StatusOK
+	response := types.GCResponse[[]types.BulkResult]{IsOK: valid, Payload: &results}
+	if valid {
+		applied, err := store.ApplyBulk(userID, payload.Operations)
+		if err != nil {
+			utils.HandleErrorResponse(w, "Failed to apply operations", http.StatusInternalServerError)
+			return
+		}
+		response.Message = fmt.Sprintf("%d operations applied", len(applied))
+		response.Payload = &applied
+	} else {
+		status = http.StatusUnprocessableEntity
+		response.Message = "No operations were applied"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// bulkChecker validates bulk operations, caching deck roles and card lookups
+// across the request.
+type bulkChecker struct {
+	userID    int
+	roles     map[int]string
+	cardDecks map[int]int
+	deleted   map[int]bool
+}
+
+// check validates and normalizes op. It returns a message for the caller when
+// the operation cannot be applied.
+func (c *bulkChecker) check(op *types.BulkOperation) (string, error) {
+	switch op.Op {
+	case types.BulkCreate:
+		if op.DeckID == 0 || op.Question == "" || op.Answer == "" {
+			return "deck_id, question and answer are required", nil
+		}
+		op.Tags = utils.NormalizeTags(op.Tags)
+		return c.checkDeck(op.DeckID)
+	case types.BulkUpdate:
+		if op.Question == "" || op.Answer == "" {
+			return "question and answer are required", nil
+		}
+		if op.Tags != nil {
+			op.Tags = utils.NormalizeTags(op.Tags)
+		}
+		return c.checkCard(op.CardID)
+	case types.BulkDelete:
+		message, err := c.checkCard(op.CardID)
+		if message == "" && err == nil {
+			c.deleted[op.CardID] = true
+		}
+		return message, err
+	case types.BulkMove:
+		if op.DeckID == 0 {
+			return "deck_id is required", nil
+		}
+		if message, err := c.checkCard(op.CardID); message != "" || err != nil {
+			return message, err
+		}
+		message, err := c.checkDeck(op.DeckID)
+		if message == "" && err == nil {
+			// Later operations on the card are checked against its new deck.
+			c.cardDecks[op.CardID] = op.DeckID
+		}
+		return message, err
+	}
+	return fmt.Sprintf("unknown operation %q", op.Op), nil
+}
+
+func (c *bulkChecker) checkDeck(deckID int) (string, error) {
+	role, ok := c.roles[deckID]
+	if !ok {
+		var err error
+		if role, err = store.GetDeckRole(deckID, c.userID); err != nil {
+			return "", err
+		}
+		c.roles[deckID] = role
+	}
+	if role == "" {
+		return "deck not found", nil
+	}
+	if !types.RoleAllows(role, types.RoleEditor) {
+		return "forbidden", nil
+	}
+	return "", nil
+}
+
+func (c *bulkChecker) checkCard(cardID int) (string, error) {
+	if cardID == 0 {
+		return "card_id is required", nil
+	}
+	if c.deleted[cardID] {
+		return "card is deleted earlier in the request", nil
+	}
+	deckID, ok := c.cardDecks[cardID]
+	if !ok {
+		var err error
+		if deckID, err = store.GetCardDeckID(cardID); err != nil {
+			return "", err
+		}
+		c.cardDecks[cardID] = deckID
+	}
+	if deckID == 0 {
+		return "card not found", nil
+	}
+	message, err := c.checkDeck(deckID)
+	if message == "deck not found" {
+		message = "card not found"
+	}
+	return message, err
+}
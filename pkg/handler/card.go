@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go-flashcards-server/pkg/db"
 	"go-flashcards-server/pkg/types"
@@ -9,17 +10,24 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/gorilla/mux"
 )
 
 type Card struct {
-	ID        int    `json:"id"`
-	DeckID    int    `json:"deck_id"`
-	Question  string `json:"question"`
-	Answer    string `json:"answer"`
-	CreatedAt string `json:"created_at"`
+	ID        int              `json:"id"`
+	DeckID    int              `json:"deck_id"`
+	Question  string           `json:"question"`
+	Answer    string           `json:"answer"`
+	CreatedAt string           `json:"created_at"`
+	UpdatedAt string           `json:"updated_at,omitempty"`
+	Tags      []string         `json:"tags,omitempty"`
+	State     *types.CardState `json:"state,omitempty"`
+	Position  int64            `json:"position"`
+	// Duplicates lists existing cards a new card duplicates.
+	Duplicates []types.DuplicateMatch `json:"duplicates,omitempty"`
 }
 
 func CreateCard(w http.ResponseWriter, r *http.Request) {
@@ -34,23 +42,39 @@ func CreateCard(w http.ResponseWriter, r *http.Request) {
 		utils.HandleErrorResponse(w, "Deck, Question, and Answer are required", http.StatusBadRequest)
 		return
 	}
-
-	statement, err := db.DB.Prepare("INSERT INTO card (deck_id, question, answer) VALUES (?, ?, ?)")
-	if err != nil {
-		utils.HandleErrorResponse(w, "Error creating statement", http.StatusInternalServerError)
+	policy, ok := duplicatePolicy(w, r)
+	if !ok {
 		return
 	}
-	defer statement.Close()
-
-	res, err := statement.Exec(card.DeckID, card.Question, card.Answer)
-	if err != nil {
-		utils.HandleErrorResponse(w, "Error creating card", http.StatusInternalServerError)
+	userID, ok := requireDeckRole(w, r, card.DeckID, types.RoleEditor)
+	if !ok {
 		return
 	}
 
-	lastID, err := res.LastInsertId()
+	if policy.Mode != types.DuplicatesAllow {
+		index, err := store.GetDuplicateIndex(userID, card.DeckID, policy)
+		if err != nil {
+			utils.HandleErrorResponse(w, "Error checking for duplicates", http.StatusInternalServerError)
+			return
+		}
+		card.Duplicates = index.Find(card.Question)
+		if len(card.Duplicates) > 0 && policy.Mode == types.DuplicatesReject {
+			response := types.GCResponse[[]types.DuplicateMatch]{
+				IsOK:    false,
+				Message: "Card duplicates existing cards",
+				Payload: &card.Duplicates,
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	card.Tags = utils.NormalizeTags(card.Tags)
+	lastID, err := store.CreateCard(card.DeckID, card.Question, card.Answer, card.Tags)
 	if err != nil {
-		utils.HandleErrorResponse(w, "Error getting card ID", http.StatusInternalServerError)
+		utils.HandleErrorResponse(w, "Error creating card", http.StatusInternalServerError)
 		return
 	}
 	card.ID = int(lastID)
@@ -66,39 +90,83 @@ func CreateCard(w http.ResponseWriter, r *http.Request) {
 
 func GetCardsByDeck(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	deckID := params["deck_id"]
-
-	if deckID == "" {
+	deckID, err := strconv.Atoi(params["deck_id"])
+	if err != nil {
 		utils.HandleErrorResponse(w, "Deck is required", http.StatusBadRequest)
 		return
 	}
+	userID, ok := requireDeckRole(w, r, deckID, types.RoleViewer)
+	if !ok {
+		return
+	}
+
+	filter, ok := listFilter(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	filter.Tag = strings.ToLower(strings.TrimSpace(query.Get("tag")))
+	filter.Status = query.Get("status")
+	switch filter.Status {
+	case "", types.CardStatusNew, types.CardStatusReview, types.CardStatusDue:
+	default:
+		utils.HandleErrorResponse(w, "Status must be new, review or due", http.StatusBadRequest)
+		return
+	}
 
-	rows, err := db.DB.Query("SELECT id, deck_id, question, answer, created_at FROM card WHERE deck_id = ?", deckID)
+	page, nextCursor, err := store.ListCards(userID, deckID, filter)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		utils.HandleErrorResponse(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		utils.HandleErrorResponse(w, "Error retrieving cards", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	var cards []Card
-	for rows.Next() {
-		var card Card
-		if err := rows.Scan(&card.ID, &card.DeckID, &card.Question, &card.Answer, &card.CreatedAt); err != nil {
-			log.Printf("Error scanning flashcard: %v", err.Error())
-			utils.HandleErrorResponse(w, "Error scanning flashcard", http.StatusInternalServerError)
-			return
+	// An empty deck is reported as before; an empty filtered page is not an
+	// error.
+	filtered := filter.Query != "" || filter.Tag != "" || filter.Status != "" || filter.Cursor != ""
+	if len(page) == 0 && !filtered {
+		utils.HandleErrorResponse(w, "No flashcards found for this deck", http.StatusNotFound)
+		return
+	}
+	cards := make([]Card, len(page))
+	cardIDs := make([]int, len(page))
+	for i, card := range page {
+		cardIDs[i] = card.ID
+		cards[i] = Card{
+			ID:        card.ID,
+			DeckID:    card.DeckID,
+			Question:  card.Question,
+			Answer:    card.Answer,
+			CreatedAt: card.CreatedAt,
+			UpdatedAt: card.UpdatedAt,
+			Position:  card.Position,
 		}
-		cards = append(cards, card)
 	}
 
-	if len(cards) == 0 {
-		utils.HandleErrorResponse(w, "No flashcards found for this deck", http.StatusNotFound)
+	states, err := store.GetCardStates(userID, cardIDs)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error retrieving card states", http.StatusInternalServerError)
+		return
+	}
+	tags, err := store.GetCardTags(cardIDs)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error retrieving card tags", http.StatusInternalServerError)
 		return
 	}
+	for i := range cards {
+		if state, ok := states[cards[i].ID]; ok {
+			cards[i].State = &state
+		}
+		cards[i].Tags = tags[cards[i].ID]
+	}
 	response := types.GCResponse[[]Card]{
-		IsOK:    true,
-		Message: "Cards Retrieved",
-		Payload: &cards,
+		IsOK:       true,
+		Message:    "Cards Retrieved",
+		Payload:    &cards,
+		NextCursor: nextCursor,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -112,6 +180,10 @@ func UpdateCard(w http.ResponseWriter, r *http.Request) {
 		utils.HandleErrorResponse(w, "Invalid card id", http.StatusBadRequest)
 		return
 	}
+	userID, ok := requireCardRole(w, r, cardID, types.RoleEditor)
+	if !ok {
+		return
+	}
 	var payload struct {
 		Question string
 		Answer   string
@@ -127,13 +199,13 @@ func UpdateCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rowsAffected, err := db.UpdateCard(cardID, payload.Question, payload.Answer)
+	found, err := store.UpdateCard(cardID, userID, payload.Question, payload.Answer)
 	if err != nil {
 		utils.HandleErrorResponse(w, "Failed to update card", http.StatusInternalServerError)
 		return
 	}
 
-	if rowsAffected == 0 {
+	if !found {
 		utils.HandleErrorResponse(w, "Card not found", http.StatusNotFound)
 		return
 	}
@@ -159,7 +231,10 @@ func DeleteCard(w http.ResponseWriter, r *http.Request) {
 		utils.HandleErrorResponse(w, "Invalid card ID", http.StatusBadRequest)
 		return
 	}
-	rowsAffected, err := db.DeleteCard(cardID)
+	if _, ok := requireCardRole(w, r, cardID, types.RoleEditor); !ok {
+		return
+	}
+	rowsAffected, err := store.DeleteCard(cardID)
 	if err != nil {
 		log.Printf("Error deleting card %v\n", err)
 		utils.HandleErrorResponse(w, "Error deleting card", http.StatusInternalServerError)
@@ -171,7 +246,7 @@ func DeleteCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	message := fmt.Sprintf("Card %d deleted successfully", cardID)
+	message := fmt.Sprintf("Card %d moved to the trash", cardID)
 	response := types.GCResponse[string]{
 		IsOK:    true,
 		Message: message,
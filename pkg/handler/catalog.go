@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultCatalogLimit = 50
+	maxCatalogLimit     = 100
+)
+
+type PublishRequest struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Language    string   `json:"language"`
+	Tags        []string `json:"tags"`
+}
+
+type CatalogDeckPayload struct {
+	Entry types.CatalogEntry `json:"entry"`
+	Cards []types.Card       `json:"cards"`
+}
+
+func PublishDeck(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	deckID, err := strconv.Atoi(params["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return
+	}
+	userID, ok := requireDeckRole(w, r, deckID, types.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var payload PublishRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	payload.Title = strings.TrimSpace(payload.Title)
+	if payload.Title == "" {
+		utils.HandleErrorResponse(w, "Title is required", http.StatusBadRequest)
+		return
+	}
+
+	entryID, err := db.PublishDeck(types.CatalogEntry{
+		DeckID:      deckID,
+		UserID:      userID,
+		Title:       payload.Title,
+		Description: strings.TrimSpace(payload.Description),
+		Language:    strings.ToLower(strings.TrimSpace(payload.Language)),
+		Tags:        utils.NormalizeTags(payload.Tags),
+	})
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to publish deck", http.StatusInternalServerError)
+		return
+	}
+
+	entry, err := db.GetCatalogEntry(int(entryID))
+	if err != nil || entry == nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve catalog entry", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[types.CatalogEntry]{
+		IsOK:    true,
+		Message: "Deck Published",
+		Payload: entry,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func UnpublishDeck(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	deckID, err := strconv.Atoi(params["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireDeckRole(w, r, deckID, types.RoleOwner); !ok {
+		return
+	}
+
+	rowsAffected, err := db.UnpublishDeck(deckID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to unpublish deck", http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected == 0 {
+		utils.HandleErrorResponse(w, "Deck is not published", http.StatusNotFound)
+		return
+	}
+
+	message := fmt.Sprintf("Deck %d unpublished", deckID)
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCatalog lists published decks. It is served without authentication.
+func GetCatalog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := types.CatalogFilter{
+		Query:    strings.TrimSpace(query.Get("q")),
+		Language: strings.ToLower(strings.TrimSpace(query.Get("language"))),
+		Tag:      strings.ToLower(strings.TrimSpace(query.Get("tag"))),
+		Limit:    defaultCatalogLimit,
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			utils.HandleErrorResponse(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = min(n, maxCatalogLimit)
+	}
+	if offset := query.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			utils.HandleErrorResponse(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		filter.Offset = n
+	}
+
+	entries, err := db.SearchCatalog(filter)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to search catalog", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[[]types.CatalogEntry]{
+		IsOK:    true,
+		Message: "Catalog retrieved",
+		Payload: &entries,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCatalogDeck returns a published deck with its cards. It is served
+// without authentication.
+func GetCatalogDeck(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	entryID, err := strconv.Atoi(params["catalog_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid catalog id", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := db.GetCatalogEntry(entryID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve catalog entry", http.StatusInternalServerError)
+		return
+	}
+	if entry == nil {
+		utils.HandleErrorResponse(w, "Catalog entry not found", http.StatusNotFound)
+		return
+	}
+
+	cards, err := db.GetCardsByDeck(entry.DeckID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error retrieving cards", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[CatalogDeckPayload]{
+		IsOK:    true,
+		Message: "Catalog entry retrieved",
+		Payload: &CatalogDeckPayload{
+			Entry: *entry,
+			Cards: cards,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CloneCatalogDeck copies a published deck and its cards into the caller's
+// decks.
+func CloneCatalogDeck(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	params := mux.Vars(r)
+	entryID, err := strconv.Atoi(params["catalog_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid catalog id", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := db.GetCatalogEntry(entryID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve catalog entry", http.StatusInternalServerError)
+		return
+	}
+	if entry == nil {
+		utils.HandleErrorResponse(w, "Catalog entry not found", http.StatusNotFound)
+		return
+	}
+
+	deckID, err := db.CloneDeck(userID, entry.DeckID, entry.Title)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to clone deck", http.StatusInternalServerError)
+		return
+	}
+	if err := db.IncrementCloneCount(entryID); err != nil {
+		log.Printf("Error updating clone count: %v", err)
+	}
+
+	response := types.GCResponse[DeckPayload]{
+		IsOK:    true,
+		Message: "Deck Cloned",
+		Payload: &DeckPayload{
+			ID:   int(deckID),
+			Name: entry.Title,
+			Role: types.RoleOwner,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
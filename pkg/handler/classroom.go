@@ -0,0 +1,361 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// generateJoinCode returns a short code without easily confused characters.
+func generateJoinCode() (string, error) {
+	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	code := make([]byte, 8)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = charset[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// requireClassroomRole checks that the caller belongs to the classroom, and is
+// a teacher when teacherOnly is set.
+func requireClassroomRole(w http.ResponseWriter, r *http.Request, teacherOnly bool) (int, int, bool) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	params := mux.Vars(r)
+	classroomID, err := strconv.Atoi(params["class_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid class id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	role, err := db.GetClassroomRole(classroomID, userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error checking class membership", http.StatusInternalServerError)
+		return 0, 0, false
+	}
+	if role == "" {
+		utils.HandleErrorResponse(w, "Class not found", http.StatusNotFound)
+		return 0, 0, false
+	}
+	if teacherOnly && role != types.ClassRoleTeacher {
+		utils.HandleErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return 0, 0, false
+	}
+	return userID, classroomID, true
+}
+
+func CreateClassroom(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" {
+		utils.HandleErrorResponse(w, "Class name is required", http.StatusBadRequest)
+		return
+	}
+
+	joinCode, err := generateJoinCode()
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to generate join code", http.StatusInternalServerError)
+		return
+	}
+	classroomID, err := db.CreateClassroom(userID, payload.Name, joinCode)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to create class", http.StatusInternalServerError)
+		return
+	}
+
+	response := types.GCResponse[types.Classroom]{
+		IsOK:    true,
+		Message: "Class Created",
+		Payload: &types.Classroom{
+			ID:       int(classroomID),
+			OwnerID:  userID,
+			Name:     payload.Name,
+			JoinCode: joinCode,
+			Role:     types.ClassRoleTeacher,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetClassrooms(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classrooms, err := db.GetClassroomsByUser(userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve classes", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[[]types.Classroom]{
+		IsOK:    true,
+		Message: "Classes retrieved",
+		Payload: &classrooms,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func JoinClassroom(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(payload.Code))
+	if code == "" {
+		utils.HandleErrorResponse(w, "Join code is required", http.StatusBadRequest)
+		return
+	}
+
+	classroom, err := db.JoinClassroom(userID, code)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to join class", http.StatusInternalServerError)
+		return
+	}
+	if classroom == nil {
+		utils.HandleErrorResponse(w, "Invalid join code", http.StatusNotFound)
+		return
+	}
+	response := types.GCResponse[types.Classroom]{
+		IsOK:    true,
+		Message: "Joined Class",
+		Payload: classroom,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RemoveClassroomMember lets a teacher remove a student, or a student leave.
+// Teachers stay with their class.
+func RemoveClassroomMember(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	params := mux.Vars(r)
+	memberID, err := strconv.Atoi(params["user_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+	_, classroomID, ok := requireClassroomRole(w, r, memberID != userID)
+	if !ok {
+		return
+	}
+	role, err := db.GetClassroomRole(classroomID, memberID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error checking class membership", http.StatusInternalServerError)
+		return
+	}
+	if role == "" {
+		utils.HandleErrorResponse(w, "Member not found", http.StatusNotFound)
+		return
+	}
+	if role == types.ClassRoleTeacher {
+		utils.HandleErrorResponse(w, "Teachers cannot be removed from their class", http.StatusForbidden)
+		return
+	}
+
+	rowsAffected, err := db.RemoveClassroomMember(classroomID, memberID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to remove student", http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected == 0 {
+		utils.HandleErrorResponse(w, "Student not found", http.StatusNotFound)
+		return
+	}
+
+	message := fmt.Sprintf("User %d removed from class %d", memberID, classroomID)
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func AssignDeck(w http.ResponseWriter, r *http.Request) {
+	_, classroomID, ok := requireClassroomRole(w, r, true)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		DeckID int    `json:"deck_id"`
+		DueAt  string `json:"due_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.DeckID == 0 {
+		utils.HandleErrorResponse(w, "Deck is required", http.StatusBadRequest)
+		return
+	}
+	var dueAt *time.Time
+	if payload.DueAt != "" {
+		parsed, err := parseDueDate(payload.DueAt)
+		if err != nil {
+			utils.HandleErrorResponse(w, "Due date must be RFC 3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		dueAt = &parsed
+	}
+	if _, ok := requireDeckRole(w, r, payload.DeckID, types.RoleEditor); !ok {
+		return
+	}
+
+	if err := db.AssignDeck(classroomID, payload.DeckID, dueAt); err != nil {
+		utils.HandleErrorResponse(w, "Failed to assign deck", http.StatusInternalServerError)
+		return
+	}
+	message := fmt.Sprintf("Deck %d assigned to class %d", payload.DeckID, classroomID)
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func UnassignDeck(w http.ResponseWriter, r *http.Request) {
+	_, classroomID, ok := requireClassroomRole(w, r, true)
+	if !ok {
+		return
+	}
+	params := mux.Vars(r)
+	deckID, err := strconv.Atoi(params["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return
+	}
+
+	rowsAffected, err := db.UnassignDeck(classroomID, deckID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to unassign deck", http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected == 0 {
+		utils.HandleErrorResponse(w, "Assignment not found", http.StatusNotFound)
+		return
+	}
+	message := fmt.Sprintf("Deck %d unassigned from class %d", deckID, classroomID)
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetAssignments(w http.ResponseWriter, r *http.Request) {
+	_, classroomID, ok := requireClassroomRole(w, r, false)
+	if !ok {
+		return
+	}
+
+	assignments, err := db.GetAssignments(classroomID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve assignments", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[[]types.Assignment]{
+		IsOK:    true,
+		Message: "Assignments retrieved",
+		Payload: &assignments,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetClassroomProgress(w http.ResponseWriter, r *http.Request) {
+	_, classroomID, ok := requireClassroomRole(w, r, true)
+	if !ok {
+		return
+	}
+
+	progress, err := db.GetClassroomProgress(classroomID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve progress", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[[]types.StudentProgress]{
+		IsOK:    true,
+		Message: "Progress retrieved",
+		Payload: &progress,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func parseDueDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// A bare date is due at the end of that day.
+	return t.Add(24*time.Hour - time.Second), nil
+}
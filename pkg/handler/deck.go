@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go-flashcards-server/pkg/db"
 	"go-flashcards-server/pkg/types"
@@ -14,8 +15,26 @@ import (
 )
 
 type DeckPayload struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Role     string `json:"role,omitempty"`
+	Position int64  `json:"position,omitempty"`
+
+	// Summary fields, filled in deck listings only.
+	CreatedAt string       `json:"created_at,omitempty"`
+	UpdatedAt string       `json:"updated_at,omitempty"`
+	Summary   *DeckSummary `json:"summary,omitempty"`
+}
+
+// DeckSummary counts a deck's cards for the caller. Learning and review counts
+// are the cards due by the end of today.
+type DeckSummary struct {
+	Cards         int     `json:"cards"`
+	New           int     `json:"new"`
+	Learning      int     `json:"learning"`
+	ReviewDue     int     `json:"review_due"`
+	LastStudiedAt *string `json:"last_studied_at"`
+	NextDueAt     *string `json:"next_due_at"`
 }
 
 func CreateDeck(w http.ResponseWriter, r *http.Request) {
@@ -37,7 +56,7 @@ func CreateDeck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	deckID, err := db.CreateDeck(userID, deck.Name)
+	deckID, err := store.CreateDeck(userID, deck.Name)
 	if err != nil {
 		utils.HandleErrorResponse(w, "Failed to create deck", http.StatusInternalServerError)
 		return
@@ -49,6 +68,7 @@ func CreateDeck(w http.ResponseWriter, r *http.Request) {
 		Payload: &DeckPayload{
 			ID:   int(deckID),
 			Name: deck.Name,
+			Role: types.RoleOwner,
 		},
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -56,6 +76,8 @@ func CreateDeck(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// GetDecks lists the caller's decks a page at a time. Besides the options of
+// listFilter it takes ?role=owner|editor|viewer.
 func GetDecks(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserIdFromContext(r)
 	if err != nil {
@@ -63,17 +85,33 @@ func GetDecks(w http.ResponseWriter, r *http.Request) {
 		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	filter, ok := listFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.Role = r.URL.Query().Get("role")
+	switch filter.Role {
+	case "", types.RoleOwner, types.RoleEditor, types.RoleViewer:
+	default:
+		utils.HandleErrorResponse(w, "Role must be owner, editor or viewer", http.StatusBadRequest)
+		return
+	}
 
-	decks, err := db.GetDecksByUser(userID)
+	decks, nextCursor, err := store.ListDecks(userID, filter)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		utils.HandleErrorResponse(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		utils.HandleErrorResponse(w, "Failed to retrieve decks", http.StatusInternalServerError)
 		return
 	}
 	payload := mapToDeckPayload(decks)
 	response := types.GCResponse[[]DeckPayload]{
-		IsOK:    true,
-		Message: "Decks retrieved",
-		Payload: &payload,
+		IsOK:       true,
+		Message:    "Decks retrieved",
+		Payload:    &payload,
+		NextCursor: nextCursor,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -84,8 +122,20 @@ func mapToDeckPayload(decks []types.Deck) []DeckPayload {
 	payload := make([]DeckPayload, len(decks))
 	for i, d := range decks {
 		payload[i] = DeckPayload{
-			ID:   d.ID,
-			Name: d.Name,
+			ID:        d.ID,
+			Name:      d.Name,
+			Role:      d.Role,
+			Position:  d.Position,
+			CreatedAt: d.CreatedAt,
+			UpdatedAt: d.UpdatedAt,
+			Summary: &DeckSummary{
+				Cards:         d.CardCount,
+				New:           d.NewCount,
+				Learning:      d.LearningCount,
+				ReviewDue:     d.ReviewCount,
+				LastStudiedAt: d.LastStudiedAt,
+				NextDueAt:     d.NextDueAt,
+			},
 		}
 	}
 	return payload
@@ -98,6 +148,9 @@ func UpdateDeck(w http.ResponseWriter, r *http.Request) {
 		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
 		return
 	}
+	if _, ok := requireDeckRole(w, r, deckID, types.RoleEditor); !ok {
+		return
+	}
 
 	var deck DeckPayload
 	if err = json.NewDecoder(r.Body).Decode(&deck); err != nil {
@@ -110,7 +163,7 @@ func UpdateDeck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rowsAffected, err := db.UpdateDeck(deckID, deck.Name)
+	rowsAffected, err := store.UpdateDeck(deckID, deck.Name)
 	if err != nil {
 		utils.HandleErrorResponse(w, "Failed to update deck", http.StatusInternalServerError)
 		return
@@ -134,6 +187,9 @@ func UpdateDeck(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// DeleteDeck moves a deck and its cards to the trash. With ?cards=move and
+// ?target=<deck_id> the cards move to the target deck instead, which the
+// caller must be able to edit.
 func DeleteDeck(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	deckID, err := strconv.Atoi(params["deck_id"])
@@ -141,8 +197,30 @@ func DeleteDeck(w http.ResponseWriter, r *http.Request) {
 		utils.HandleErrorResponse(w, "Invalid deck ID", http.StatusBadRequest)
 		return
 	}
+	query := r.URL.Query()
+	targetDeckID := 0
+	switch query.Get("cards") {
+	case "", "delete":
+	case "move":
+		targetDeckID, err = strconv.Atoi(query.Get("target"))
+		if err != nil || targetDeckID == deckID {
+			utils.HandleErrorResponse(w, "A different target deck is required to move cards", http.StatusBadRequest)
+			return
+		}
+	default:
+		utils.HandleErrorResponse(w, "Cards must be delete or move", http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireDeckRole(w, r, deckID, types.RoleOwner); !ok {
+		return
+	}
+	if targetDeckID != 0 {
+		if _, ok := checkDeckRole(w, r, targetDeckID, types.RoleEditor, "Target deck not found"); !ok {
+			return
+		}
+	}
 
-	rowsAffected, err := db.DeleteDeck(deckID)
+	rowsAffected, moved, err := store.DeleteDeck(deckID, targetDeckID)
 	if err != nil {
 		log.Printf("Error deleting deck %v\n", err)
 		utils.HandleErrorResponse(w, "Error deleting deck", http.StatusInternalServerError)
@@ -153,7 +231,10 @@ func DeleteDeck(w http.ResponseWriter, r *http.Request) {
 		utils.HandleErrorResponse(w, "Deck not found", http.StatusNotFound)
 		return
 	}
-	message := fmt.Sprintf("Deck %d deleted successfully", deckID)
+	message := fmt.Sprintf("Deck %d moved to the trash", deckID)
+	if targetDeckID != 0 {
+		message += fmt.Sprintf(", %d cards moved to deck %d", moved, targetDeckID)
+	}
 	response := types.GCResponse[string]{
 		IsOK:    true,
 		Message: message,
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-flashcards-server/pkg/dedupe"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+var tooManyForSimilarity = fmt.Sprintf("Near-duplicate matching is limited to %d cards; leave out similarity or use the deck scope", dedupe.MaxFuzzyCards)
+
+// duplicatePolicy reads ?duplicates=allow|warn|reject (default warn),
+// ?scope=deck|collection (default deck) and ?similarity, an optional threshold
+// between 0 and 1 for near duplicates. Without it only questions that are the
+// same once normalized match.
+func duplicatePolicy(w http.ResponseWriter, r *http.Request) (types.DuplicatePolicy, bool) {
+	query := r.URL.Query()
+	policy := types.DuplicatePolicy{
+		Mode:  query.Get("duplicates"),
+		Scope: query.Get("scope"),
+	}
+	switch policy.Mode {
+	case "":
+		policy.Mode = types.DuplicatesWarn
+	case types.DuplicatesAllow, types.DuplicatesWarn, types.DuplicatesReject:
+	default:
+		utils.HandleErrorResponse(w, "Duplicates must be allow, warn or reject", http.StatusBadRequest)
+		return policy, false
+	}
+	switch policy.Scope {
+	case "":
+		policy.Scope = types.DuplicateScopeDeck
+	case types.DuplicateScopeDeck, types.DuplicateScopeCollection:
+	default:
+		utils.HandleErrorResponse(w, "Scope must be deck or collection", http.StatusBadRequest)
+		return policy, false
+	}
+	if value := query.Get("similarity"); value != "" {
+		similarity, err := strconv.ParseFloat(value, 64)
+		if err != nil || similarity < 0 || similarity > 1 {
+			utils.HandleErrorResponse(w, "Similarity must be between 0 and 1", http.StatusBadRequest)
+			return policy, false
+		}
+		policy.Similarity = similarity
+	}
+	return policy, true
+}
+
+// GetDeckDuplicates groups the deck's cards that duplicate each other or,
+// with ?scope=collection, cards elsewhere in the user's decks.
+func GetDeckDuplicates(w http.ResponseWriter, r *http.Request) {
+	deckID, err := strconv.Atoi(mux.Vars(r)["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return
+	}
+	policy, ok := duplicatePolicy(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := requireDeckRole(w, r, deckID, types.RoleViewer)
+	if !ok {
+		return
+	}
+
+	index, err := store.GetDuplicateIndex(userID, deckID, policy)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve cards", http.StatusInternalServerError)
+		return
+	}
+	if err := index.CheckFuzzy(0); err != nil {
+		utils.HandleErrorResponse(w, tooManyForSimilarity, http.StatusUnprocessableEntity)
+		return
+	}
+	groups := index.Groups(func(card types.DuplicateCard) bool { return card.DeckID == deckID })
+
+	response := types.GCResponse[[]types.DuplicateGroup]{
+		IsOK:    true,
+		Message: "Duplicates retrieved",
+		Payload: &groups,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/exporter"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ExportDeck streams a deck as ?format=csv, json, apkg or md. The requesting
+// user's review state is included when ?scheduling=true.
+func ExportDeck(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	deckID, err := strconv.Atoi(params["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return
+	}
+	userID, ok := requireDeckRole(w, r, deckID, types.RoleViewer)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType, ok := exporter.ContentType(format)
+	if !ok {
+		utils.HandleErrorResponse(w, "Format must be csv, json, apkg or md", http.StatusBadRequest)
+		return
+	}
+	withState := r.URL.Query().Get("scheduling") == "true"
+
+	deck, err := db.GetExportDeck(deckID, userID, withState)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to export deck", http.StatusInternalServerError)
+		return
+	}
+
+	// A package is built in full first, so a failure is still reported as
+	// an error rather than as an empty download. Text formats are streamed.
+	var apkg bytes.Buffer
+	if format == "apkg" {
+		if err := exporter.WriteAnkiPackage(&apkg, deck, withState, nil); err != nil {
+			log.Printf("Error exporting deck %d as %s: %v", deckID, format, err)
+			utils.HandleErrorResponse(w, "Failed to export deck", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", attachment(exporter.Filename(deck.Name, format)))
+	w.WriteHeader(http.StatusOK)
+
+	if format == "apkg" {
+		_, err = apkg.WriteTo(w)
+	} else {
+		err = exporter.Write(w, format, deck, withState, nil)
+	}
+	// Headers are already sent, so a failure can only be logged.
+	if err != nil {
+		log.Printf("Error exporting deck %d as %s: %v", deckID, format, err)
+	}
+}
+
+func attachment(filename string) string {
+	return fmt.Sprintf("attachment; filename=%q", filename)
+}
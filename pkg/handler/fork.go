@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// CloneDeck copies a deck shared with the caller into their own decks.
+func CloneDeck(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	sourceDeckID, err := strconv.Atoi(params["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return
+	}
+	userID, ok := requireDeckRole(w, r, sourceDeckID, types.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var payload DeckPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.Name == "" {
+		utils.HandleErrorResponse(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	deckID, err := db.CloneDeck(userID, sourceDeckID, payload.Name)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to clone deck", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[DeckPayload]{
+		IsOK:    true,
+		Message: "Deck Cloned",
+		Payload: &DeckPayload{
+			ID:   int(deckID),
+			Name: payload.Name,
+			Role: types.RoleOwner,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// loadUpstream returns the fork link of a deck the caller holds a role on,
+// checking that its upstream deck is still visible to them.
+func loadUpstream(w http.ResponseWriter, r *http.Request, required string) (*types.DeckFork, int, bool) {
+	params := mux.Vars(r)
+	deckID, err := strconv.Atoi(params["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return nil, 0, false
+	}
+	userID, ok := requireDeckRole(w, r, deckID, required)
+	if !ok {
+		return nil, 0, false
+	}
+
+	fork, err := db.GetDeckFork(deckID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve upstream", http.StatusInternalServerError)
+		return nil, 0, false
+	}
+	if fork == nil {
+		utils.HandleErrorResponse(w, "Deck has no upstream", http.StatusNotFound)
+		return nil, 0, false
+	}
+
+	role, err := store.GetDeckRole(fork.SourceDeckID, userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve upstream", http.StatusInternalServerError)
+		return nil, 0, false
+	}
+	if role == "" {
+		published, err := db.IsDeckPublished(fork.SourceDeckID)
+		if err != nil {
+			utils.HandleErrorResponse(w, "Failed to retrieve upstream", http.StatusInternalServerError)
+			return nil, 0, false
+		}
+		if !published {
+			utils.HandleErrorResponse(w, "Upstream deck is no longer available", http.StatusNotFound)
+			return nil, 0, false
+		}
+	}
+	return fork, userID, true
+}
+
+func GetUpstream(w http.ResponseWriter, r *http.Request) {
+	fork, _, ok := loadUpstream(w, r, types.RoleViewer)
+	if !ok {
+		return
+	}
+
+	diff, err := db.DiffUpstream(*fork)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to compare with upstream", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[types.UpstreamDiff]{
+		IsOK:    true,
+		Message: "Upstream changes retrieved",
+		Payload: diff,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ApplyUpstream merges upstream changes. Conflicting local edits are kept
+// unless the request sets take_upstream.
+func ApplyUpstream(w http.ResponseWriter, r *http.Request) {
+	fork, userID, ok := loadUpstream(w, r, types.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		TakeUpstream bool `json:"take_upstream"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := db.ApplyUpstream(*fork, userID, payload.TakeUpstream)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to apply upstream changes", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[types.UpstreamMergeResult]{
+		IsOK:    true,
+		Message: "Upstream changes applied",
+		Payload: result,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
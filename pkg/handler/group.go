@@ -0,0 +1,540 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// requireGroupMember checks that the caller belongs to the group in the path.
+func requireGroupMember(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	params := mux.Vars(r)
+	groupID, err := strconv.Atoi(params["group_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid group id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	member, err := db.IsGroupMember(groupID, userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error checking group membership", http.StatusInternalServerError)
+		return 0, 0, false
+	}
+	if !member {
+		utils.HandleErrorResponse(w, "Group not found", http.StatusNotFound)
+		return 0, 0, false
+	}
+	return userID, groupID, true
+}
+
+func CreateGroup(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" {
+		utils.HandleErrorResponse(w, "Group name is required", http.StatusBadRequest)
+		return
+	}
+
+	inviteCode, err := generateJoinCode()
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to generate invite code", http.StatusInternalServerError)
+		return
+	}
+	groupID, err := db.CreateGroup(userID, payload.Name, inviteCode)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to create group", http.StatusInternalServerError)
+		return
+	}
+
+	response := types.GCResponse[types.Group]{
+		IsOK:    true,
+		Message: "Group Created",
+		Payload: &types.Group{
+			ID:         int(groupID),
+			OwnerID:    userID,
+			Name:       payload.Name,
+			InviteCode: inviteCode,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetGroups(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groups, err := db.GetGroupsByUser(userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve groups", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[[]types.Group]{
+		IsOK:    true,
+		Message: "Groups retrieved",
+		Payload: &groups,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func JoinGroup(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(payload.Code))
+	if code == "" {
+		utils.HandleErrorResponse(w, "Invite code is required", http.StatusBadRequest)
+		return
+	}
+
+	group, err := db.JoinGroupByCode(userID, code)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to join group", http.StatusInternalServerError)
+		return
+	}
+	if group == nil {
+		utils.HandleErrorResponse(w, "Invalid invite code", http.StatusNotFound)
+		return
+	}
+	response := types.GCResponse[types.Group]{
+		IsOK:    true,
+		Message: "Joined Group",
+		Payload: group,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func InviteToGroup(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := requireGroupMember(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.Email == "" {
+		utils.HandleErrorResponse(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	inviteeID, err := db.GetUserIDByEmail(payload.Email)
+	if err != nil {
+		utils.HandleErrorResponse(w, "User not found", http.StatusNotFound)
+		return
+	}
+	member, err := db.IsGroupMember(groupID, inviteeID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error checking group membership", http.StatusInternalServerError)
+		return
+	}
+	if member {
+		utils.HandleErrorResponse(w, "User is already a member", http.StatusConflict)
+		return
+	}
+	if err := db.InviteToGroup(groupID, inviteeID, userID); err != nil {
+		utils.HandleErrorResponse(w, "Failed to invite user", http.StatusInternalServerError)
+		return
+	}
+
+	message := fmt.Sprintf("%s invited to group %d", payload.Email, groupID)
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetGroupInvites(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	invites, err := db.GetGroupInvites(userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve invites", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[[]types.GroupInvite]{
+		IsOK:    true,
+		Message: "Invites retrieved",
+		Payload: &invites,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func AcceptGroupInvite(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	params := mux.Vars(r)
+	groupID, err := strconv.Atoi(params["group_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid group id", http.StatusBadRequest)
+		return
+	}
+
+	accepted, err := db.AcceptGroupInvite(groupID, userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to accept invite", http.StatusInternalServerError)
+		return
+	}
+	if !accepted {
+		utils.HandleErrorResponse(w, "Invite not found", http.StatusNotFound)
+		return
+	}
+	message := fmt.Sprintf("Joined group %d", groupID)
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeclineGroupInvite(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	params := mux.Vars(r)
+	groupID, err := strconv.Atoi(params["group_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid group id", http.StatusBadRequest)
+		return
+	}
+
+	rowsAffected, err := db.DeclineGroupInvite(groupID, userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to decline invite", http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected == 0 {
+		utils.HandleErrorResponse(w, "Invite not found", http.StatusNotFound)
+		return
+	}
+	message := fmt.Sprintf("Invite to group %d declined", groupID)
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// SetGroupVisibility lets a member opt out of (or back into) the group's
+// leaderboards and challenge listings.
+func SetGroupVisibility(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := requireGroupMember(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Hidden bool `json:"hidden"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := db.SetGroupVisibility(groupID, userID, payload.Hidden); err != nil {
+		utils.HandleErrorResponse(w, "Failed to update visibility", http.StatusInternalServerError)
+		return
+	}
+
+	message := "Visible on leaderboards"
+	if payload.Hidden {
+		message = "Hidden from leaderboards"
+	}
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func LeaveGroup(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := requireGroupMember(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := db.LeaveGroup(groupID, userID); err != nil {
+		utils.HandleErrorResponse(w, "Failed to leave group", http.StatusInternalServerError)
+		return
+	}
+	message := fmt.Sprintf("Left group %d", groupID)
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetLeaderboard ranks visible group members over one week (Monday to Monday,
+// UTC). The week defaults to the current one and can be picked with
+// ?week=YYYY-MM-DD; ?sort= is one of reviews, streak or accuracy.
+func GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	_, groupID, ok := requireGroupMember(w, r)
+	if !ok {
+		return
+	}
+
+	day := time.Now().UTC()
+	if week := r.URL.Query().Get("week"); week != "" {
+		parsed, err := time.Parse(time.DateOnly, week)
+		if err != nil {
+			utils.HandleErrorResponse(w, "Week must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		day = parsed
+	}
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = "reviews"
+	}
+	if sortBy != "reviews" && sortBy != "streak" && sortBy != "accuracy" {
+		utils.HandleErrorResponse(w, "Sort must be reviews, streak or accuracy", http.StatusBadRequest)
+		return
+	}
+
+	start := weekStart(day)
+	end := start.AddDate(0, 0, 7)
+	entries, err := db.GetLeaderboard(groupID, start, end)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve leaderboard", http.StatusInternalServerError)
+		return
+	}
+	rankLeaderboard(entries, sortBy)
+
+	response := types.GCResponse[types.Leaderboard]{
+		IsOK:    true,
+		Message: "Leaderboard retrieved",
+		Payload: &types.Leaderboard{
+			GroupID:   groupID,
+			WeekStart: start.Format(time.DateOnly),
+			WeekEnd:   end.Format(time.DateOnly),
+			SortBy:    sortBy,
+			Entries:   entries,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// weekStart returns midnight UTC of the Monday on or before t.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+// rankLeaderboard sorts entries by the chosen metric, breaking ties by review
+// count, and assigns shared ranks to equal scores.
+func rankLeaderboard(entries []types.LeaderboardEntry, sortBy string) {
+	score := func(e types.LeaderboardEntry) float64 {
+		switch sortBy {
+		case "streak":
+			return float64(e.Streak)
+		case "accuracy":
+			return e.Accuracy
+		default:
+			return float64(e.Reviews)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		si, sj := score(entries[i]), score(entries[j])
+		if si != sj {
+			return si > sj
+		}
+		return entries[i].Reviews > entries[j].Reviews
+	})
+	for i := range entries {
+		if i > 0 && score(entries[i]) == score(entries[i-1]) {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+}
+
+func CreateChallenge(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := requireGroupMember(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Title    string `json:"title"`
+		Metric   string `json:"metric"`
+		Target   int    `json:"target"`
+		StartsAt string `json:"starts_at"`
+		EndsAt   string `json:"ends_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	payload.Title = strings.TrimSpace(payload.Title)
+	if payload.Title == "" || payload.Target <= 0 {
+		utils.HandleErrorResponse(w, "Title and a positive target are required", http.StatusBadRequest)
+		return
+	}
+	if payload.Metric == "" {
+		payload.Metric = types.ChallengeMetricReviews
+	}
+	if payload.Metric != types.ChallengeMetricReviews && payload.Metric != types.ChallengeMetricCorrect && payload.Metric != types.ChallengeMetricDays {
+		utils.HandleErrorResponse(w, "Metric must be reviews, correct or days", http.StatusBadRequest)
+		return
+	}
+
+	// Without explicit bounds a challenge covers the current week.
+	startsAt := weekStart(time.Now())
+	endsAt := startsAt.AddDate(0, 0, 7)
+	var err error
+	if payload.StartsAt != "" {
+		if startsAt, err = time.Parse(time.RFC3339, payload.StartsAt); err != nil {
+			utils.HandleErrorResponse(w, "starts_at must be RFC 3339", http.StatusBadRequest)
+			return
+		}
+	}
+	if payload.EndsAt != "" {
+		if endsAt, err = time.Parse(time.RFC3339, payload.EndsAt); err != nil {
+			utils.HandleErrorResponse(w, "ends_at must be RFC 3339", http.StatusBadRequest)
+			return
+		}
+	}
+	if !endsAt.After(startsAt) {
+		utils.HandleErrorResponse(w, "Challenge must end after it starts", http.StatusBadRequest)
+		return
+	}
+
+	challenge := types.Challenge{
+		GroupID:   groupID,
+		CreatedBy: userID,
+		Title:     payload.Title,
+		Metric:    payload.Metric,
+		Target:    payload.Target,
+		StartsAt:  startsAt.UTC().Format(time.DateTime),
+		EndsAt:    endsAt.UTC().Format(time.DateTime),
+	}
+	challengeID, err := db.CreateChallenge(challenge, startsAt.UTC(), endsAt.UTC())
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to create challenge", http.StatusInternalServerError)
+		return
+	}
+	challenge.ID = int(challengeID)
+
+	response := types.GCResponse[types.Challenge]{
+		IsOK:    true,
+		Message: "Challenge Created",
+		Payload: &challenge,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetChallenges(w http.ResponseWriter, r *http.Request) {
+	_, groupID, ok := requireGroupMember(w, r)
+	if !ok {
+		return
+	}
+
+	progress, err := db.GetChallengeProgress(groupID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve challenges", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now().UTC().Format(time.DateTime)
+	for i := range progress {
+		progress[i].Active = progress[i].StartsAt <= now && now < progress[i].EndsAt
+	}
+
+	response := types.GCResponse[[]types.ChallengeProgress]{
+		IsOK:    true,
+		Message: "Challenges retrieved",
+		Payload: &progress,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
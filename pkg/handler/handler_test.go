@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-flashcards-server/pkg/config"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestServer wires the user routes and RegisterDeckRoutes, as main does,
+// over a fresh MemoryStore.
+func newTestServer(t *testing.T) (http.Handler, *db.MemoryStore) {
+	t.Helper()
+	config.JWTSecretKey = []byte("test secret")
+	memory := db.NewMemoryStore()
+	UseStore(memory)
+	t.Cleanup(func() { UseStore(db.SQLStore{}) })
+
+	r := mux.NewRouter()
+	r.HandleFunc("/signup", SignUp).Methods("POST")
+	r.HandleFunc("/login", Login).Methods("POST")
+
+	RegisterDeckRoutes(r)
+	return r, memory
+}
+
+// testClient sends requests as one signed-up user.
+type testClient struct {
+	t       *testing.T
+	handler http.Handler
+	cookie  *http.Cookie
+	userID  int
+	// message is the Message of the last response.
+	message string
+}
+
+func signUp(t *testing.T, handler http.Handler, email string) *testClient {
+	t.Helper()
+	c := &testClient{t: t, handler: handler}
+	var user UserPayload
+	status := c.do("POST", "/signup", User{FirstName: "Test", LastName: "User", Email: email, Password: "secret"}, &user)
+	if status != http.StatusOK {
+		t.Fatalf("signing up %s: status %d", email, status)
+	}
+	c.userID = user.ID
+	return c
+}
+
+// do sends a request with body encoded as JSON, decodes the response payload
+// into payload when it is not nil, and returns the status code. The response
+// message is kept in c.message.
+func (c *testClient) do(method, path string, body, payload any) int {
+	c.t.Helper()
+	var encoded bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&encoded).Encode(body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, path, &encoded)
+	if c.cookie != nil {
+		r.AddCookie(c.cookie)
+	}
+	w := httptest.NewRecorder()
+	c.handler.ServeHTTP(w, r)
+
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "token" {
+			c.cookie = cookie
+		}
+	}
+	response := types.GCResponse[json.RawMessage]{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		c.t.Fatalf("%s %s: decoding response: %v", method, path, err)
+	}
+	c.message = response.Message
+	if payload != nil {
+		if response.Payload != nil {
+			if err := json.Unmarshal(*response.Payload, payload); err != nil {
+				c.t.Fatalf("%s %s: decoding payload: %v", method, path, err)
+			}
+		}
+	}
+	return w.Code
+}
+
+func (c *testClient) expect(method, path string, body any, want int) {
+	c.t.Helper()
+	if status := c.do(method, path, body, nil); status != want {
+		c.t.Errorf("%s %s: status %d, want %d", method, path, status, want)
+	}
+}
+
+func (c *testClient) createDeck(name string) int {
+	c.t.Helper()
+	var deck DeckPayload
+	if status := c.do("POST", "/deck/create", DeckPayload{Name: name}, &deck); status != http.StatusOK {
+		c.t.Fatalf("creating deck %q: status %d", name, status)
+	}
+	return deck.ID
+}
+
+func (c *testClient) createCard(deckID int, question, answer string, tags ...string) int {
+	c.t.Helper()
+	var card Card
+	body := Card{DeckID: deckID, Question: question, Answer: answer, Tags: tags}
+	if status := c.do("POST", "/card/create", body, &card); status != http.StatusOK {
+		c.t.Fatalf("creating card %q: status %d", question, status)
+	}
+	return card.ID
+}
+
+func TestLoginChecksPassword(t *testing.T) {
+	server, _ := newTestServer(t)
+	signUp(t, server, "ana@example.com")
+
+	c := &testClient{t: t, handler: server}
+	c.expect("POST", "/login", Credentials{Email: "ana@example.com", Password: "wrong"}, http.StatusBadRequest)
+	c.expect("POST", "/login", Credentials{Email: "nobody@example.com", Password: "secret"}, http.StatusBadRequest)
+	c.expect("POST", "/login", Credentials{Email: "ana@example.com", Password: "secret"}, http.StatusOK)
+	c.expect("GET", "/deck", nil, http.StatusOK)
+}
+
+func TestRoutesRequireLogin(t *testing.T) {
+	server, _ := newTestServer(t)
+	c := &testClient{t: t, handler: server}
+	c.expect("GET", "/deck", nil, http.StatusUnauthorized)
+	c.expect("POST", "/card/create", Card{DeckID: 1, Question: "q", Answer: "a"}, http.StatusUnauthorized)
+}
+
+func TestDeckLifecycle(t *testing.T) {
+	server, _ := newTestServer(t)
+	ana := signUp(t, server, "ana@example.com")
+	deckID := ana.createDeck("Spanish")
+	ana.createDeck("French")
+	ana.createCard(deckID, "hola", "hello")
+
+	var decks []DeckPayload
+	if status := ana.do("GET", "/deck?sort=alpha", nil, &decks); status != http.StatusOK {
+		t.Fatalf("listing decks: status %d", status)
+	}
+	if len(decks) != 2 || decks[0].Name != "French" || decks[1].Name != "Spanish" {
+		t.Fatalf("decks = %+v, want French and Spanish", decks)
+	}
+	if decks[1].Summary == nil || decks[1].Summary.Cards != 1 || decks[1].Summary.New != 1 {
+		t.Errorf("summary of Spanish = %+v, want one new card", decks[1].Summary)
+	}
+
+	ana.expect("PUT", "/deck/update/"+strconv.Itoa(deckID), DeckPayload{Name: "Español"}, http.StatusOK)
+	ana.expect("DELETE", "/deck/delete/"+strconv.Itoa(deckID), nil, http.StatusOK)
+	ana.expect("PUT", "/deck/update/"+strconv.Itoa(deckID), DeckPayload{Name: "Spanish"}, http.StatusNotFound)
+	ana.expect("DELETE", "/deck/delete/"+strconv.Itoa(deckID), nil, http.StatusNotFound)
+}
+
+func TestCardLifecycle(t *testing.T) {
+	server, _ := newTestServer(t)
+	ana := signUp(t, server, "ana@example.com")
+	deckID := ana.createDeck("Spanish")
+	first := ana.createCard(deckID, "hola", "hello", "Greetings")
+	ana.createCard(deckID, "adiós", "goodbye")
+	ana.createCard(deckID, "gato", "cat", "animals")
+
+	var cards []Card
+	if status := ana.do("GET", "/card/"+strconv.Itoa(deckID)+"?limit=2", nil, &cards); status != http.StatusOK {
+		t.Fatalf("listing cards: status %d", status)
+	}
+	if len(cards) != 2 || cards[0].ID != first {
+		t.Fatalf("first page = %+v, want two cards starting with %d", cards, first)
+	}
+	if len(cards[0].Tags) != 1 || cards[0].Tags[0] != "greetings" {
+		t.Errorf("tags of first card = %v, want [greetings]", cards[0].Tags)
+	}
+
+	var tagged []Card
+	ana.do("GET", "/card/"+strconv.Itoa(deckID)+"?tag=animals", nil, &tagged)
+	if len(tagged) != 1 || tagged[0].Question != "gato" {
+		t.Errorf("cards tagged animals = %+v, want gato", tagged)
+	}
+
+	ana.expect("PUT", "/card/update/"+strconv.Itoa(first), Card{Question: "buenos días", Answer: "good morning"}, http.StatusOK)
+	ana.expect("DELETE", "/card/delete/"+strconv.Itoa(first), nil, http.StatusOK)
+	ana.expect("PUT", "/card/update/"+strconv.Itoa(first), Card{Question: "hola", Answer: "hello"}, http.StatusNotFound)
+	ana.expect("DELETE", "/card/delete/"+strconv.Itoa(first), nil, http.StatusNotFound)
+}
+
+func TestShareRoutes(t *testing.T) {
+	server, _ := newTestServer(t)
+	ana := signUp(t, server, "ana@example.com")
+	ben := signUp(t, server, "ben@example.com")
+	deckID := ana.createDeck("Spanish")
+
+	ana.expect("POST", "/deck/share/"+strconv.Itoa(deckID), map[string]string{"email": "nobody@example.com", "role": types.RoleViewer}, http.StatusNotFound)
+	ana.expect("POST", "/deck/share/"+strconv.Itoa(deckID), map[string]string{"email": "ben@example.com", "role": types.RoleViewer}, http.StatusOK)
+
+	var shares []types.DeckShare
+	ana.do("GET", "/deck/share/"+strconv.Itoa(deckID), nil, &shares)
+	if len(shares) != 1 || shares[0].UserID != ben.userID || shares[0].Email != "ben@example.com" || shares[0].Role != types.RoleViewer {
+		t.Errorf("shares = %+v, want ben as viewer", shares)
+	}
+	ben.expect("GET", "/card/"+strconv.Itoa(deckID), nil, http.StatusNotFound) // shared, but empty
+
+	ana.expect("DELETE", "/deck/share/"+strconv.Itoa(deckID)+"/"+strconv.Itoa(ben.userID), nil, http.StatusOK)
+	ana.expect("DELETE", "/deck/share/"+strconv.Itoa(deckID)+"/"+strconv.Itoa(ben.userID), nil, http.StatusNotFound)
+}
+
+func TestBulkCards(t *testing.T) {
+	server, _ := newTestServer(t)
+	ana := signUp(t, server, "ana@example.com")
+	deckID := ana.createDeck("Spanish")
+	otherID := ana.createDeck("Archive")
+	cardID := ana.createCard(deckID, "hola", "hello")
+	movedID := ana.createCard(deckID, "gato", "cat")
+
+	ops := BulkRequest{Operations: []types.BulkOperation{
+		{Op: types.BulkCreate, DeckID: deckID, Question: "perro", Answer: "dog", Tags: []string{"Animals"}},
+		{Op: types.BulkUpdate, CardID: cardID, Question: "hola", Answer: "hi"},
+		{Op: types.BulkMove, CardID: movedID, DeckID: otherID},
+	}}
+	var results []types.BulkResult
+	if status := ana.do("POST", "/card/bulk", ops, &results); status != http.StatusOK {
+		t.Fatalf("bulk: status %d", status)
+	}
+	if len(results) != 3 || results[0].CardID == 0 {
+		t.Fatalf("results = %+v, want three with a created card", results)
+	}
+
+	var cards []Card
+	ana.do("GET", "/card/"+strconv.Itoa(deckID), nil, &cards)
+	if len(cards) != 2 || cards[0].Answer != "hi" || cards[1].Question != "perro" || len(cards[1].Tags) != 1 {
+		t.Errorf("cards = %+v, want the updated and the created card", cards)
+	}
+
+	// One invalid operation applies none of them.
+	ops = BulkRequest{Operations: []types.BulkOperation{
+		{Op: types.BulkDelete, CardID: cardID},
+		{Op: types.BulkDelete, CardID: 9999},
+	}}
+	ana.expect("POST", "/card/bulk", ops, http.StatusUnprocessableEntity)
+	ana.expect("PUT", "/card/update/"+strconv.Itoa(cardID), Card{Question: "hola", Answer: "hello"}, http.StatusOK)
+}
@@ -0,0 +1,431 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/dedupe"
+	"go-flashcards-server/pkg/importer"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxImportSize   = 256 << 20
+	maxCSVSize      = 16 << 20
+	maxImportMemory = 32 << 20
+)
+
+// ImportAnki imports an uploaded Anki package (multipart field "file"). Review
+// state is carried over when ?scheduling=true.
+func ImportAnki(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	policy, ok := duplicatePolicy(w, r)
+	if !ok {
+		return
+	}
+	file, header, ok := readImportFile(w, r, maxImportSize)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	imp := importer.AnkiImporter{WithScheduling: r.URL.Query().Get("scheduling") == "true"}
+	result, err := imp.Import(file, header.Size)
+	if errors.Is(err, importer.ErrUnsupportedCollection) || errors.Is(err, importer.ErrNoCollection) ||
+		errors.Is(err, importer.ErrCollectionTooLarge) {
+		utils.HandleErrorResponse(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error reading Anki package: %v", err)
+		utils.HandleErrorResponse(w, "Invalid Anki package", http.StatusBadRequest)
+		return
+	}
+	writeImportResult(w, userID, result, policy)
+}
+
+// ImportQuizlet imports a Quizlet text export. ?term_separator and
+// ?card_separator override the tab and newline defaults.
+func ImportQuizlet(w http.ResponseWriter, r *http.Request) {
+	importText(w, r, "quizlet")
+}
+
+// ImportSuperMemo imports a SuperMemo Q&A text file.
+func ImportSuperMemo(w http.ResponseWriter, r *http.Request) {
+	importText(w, r, "supermemo")
+}
+
+// importText imports a text upload into the deck chosen by importTarget.
+func importText(w http.ResponseWriter, r *http.Request, format string) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	policy, ok := duplicatePolicy(w, r)
+	if !ok {
+		return
+	}
+
+	file, header, ok := readImportFile(w, r, maxCSVSize)
+	if !ok {
+		return
+	}
+	defer file.Close()
+	deckID, deckName, ok := importTarget(w, r, header.Filename)
+	if !ok {
+		return
+	}
+
+	imp, err := importer.New(format, importOptions(r, deckName))
+	if err != nil {
+		utils.HandleErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := imp.Import(file, header.Size)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Could not read file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range result.Decks {
+		result.Decks[i].ID = deckID
+	}
+	writeImportResult(w, userID, result, policy)
+}
+
+// importTarget resolves the deck an upload goes into: ?deck_id, which the
+// user must be able to edit, or otherwise the user's deck named ?deck or
+// after the file.
+func importTarget(w http.ResponseWriter, r *http.Request, filename string) (int, string, bool) {
+	query := r.URL.Query()
+	if value := query.Get("deck_id"); value != "" {
+		deckID, err := strconv.Atoi(value)
+		if err != nil {
+			utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+			return 0, "", false
+		}
+		if _, ok := requireDeckRole(w, r, deckID, types.RoleEditor); !ok {
+			return 0, "", false
+		}
+		deckName, err := db.GetDeckName(deckID)
+		if err != nil {
+			utils.HandleErrorResponse(w, "Failed to retrieve deck", http.StatusInternalServerError)
+			return 0, "", false
+		}
+		return deckID, deckName, true
+	}
+
+	deckName := query.Get("deck")
+	if deckName == "" {
+		deckName = strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+	if deckName == "" {
+		deckName = "Imported Cards"
+	}
+	return 0, deckName, true
+}
+
+// importOptions reads importer options from the query string. Fields are
+// trimmed unless ?trim=false.
+func importOptions(r *http.Request, deckName string) importer.Options {
+	query := r.URL.Query()
+	return importer.Options{
+		DeckName:      deckName,
+		Scheduling:    query.Get("scheduling") == "true",
+		Trim:          query.Get("trim") != "false",
+		TermSeparator: query.Get("term_separator"),
+		CardSeparator: query.Get("card_separator"),
+	}
+}
+
+// readImportFile returns the multipart "file" field of an upload limited to
+// maxSize bytes.
+func readImportFile(w http.ResponseWriter, r *http.Request, maxSize int64) (multipart.File, *multipart.FileHeader, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	if err := r.ParseMultipartForm(maxImportMemory); err != nil {
+		utils.HandleErrorResponse(w, "Invalid upload", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.HandleErrorResponse(w, "File is required", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return file, header, true
+}
+
+func writeImportResult(w http.ResponseWriter, userID int, result *importer.Result, policy types.DuplicatePolicy) {
+	report, err := db.ImportDecks(userID, result.Decks, policy, nil)
+	if errors.Is(err, dedupe.ErrTooManyCards) {
+		utils.HandleErrorResponse(w, tooManyForSimilarity, http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to import cards", http.StatusInternalServerError)
+		return
+	}
+	report.Skipped = append(report.Skipped, result.Skipped...)
+	report.MediaSkipped = result.MediaSkipped
+
+	response := types.GCResponse[types.ImportReport]{
+		IsOK:    true,
+		Message: "Import Complete",
+		Payload: report,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// PreviewCSVImport stores an uploaded CSV or TSV file (multipart field "file")
+// and returns its detected layout. The import is completed by
+// ConfirmCSVImport with the returned upload ID.
+func PreviewCSVImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	file, header, ok := readImportFile(w, r, maxCSVSize)
+	if !ok {
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+
+	preview, err := importer.PreviewCSV(content)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Could not read file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	uploadID, err := generateUploadID()
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to store upload", http.StatusInternalServerError)
+		return
+	}
+	if err := db.SaveUpload(uploadID, userID, header.Filename, content); err != nil {
+		utils.HandleErrorResponse(w, "Failed to store upload", http.StatusInternalServerError)
+		return
+	}
+	preview.UploadID = uploadID
+	preview.Filename = header.Filename
+
+	response := types.GCResponse[types.CSVPreview]{
+		IsOK:    true,
+		Message: "Preview ready",
+		Payload: preview,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+type CSVConfirmRequest struct {
+	Delimiter string           `json:"delimiter"`
+	HasHeader bool             `json:"has_header"`
+	Mapping   types.CSVMapping `json:"mapping"`
+	DeckID    int              `json:"deck_id"`
+}
+
+// ConfirmCSVImport inserts the rows of a previewed upload using the chosen
+// column mapping. Rows go into deck_id unless the mapping has a deck column
+// naming another deck. All cards are inserted in one transaction; unusable
+// rows are reported and skipped.
+func ConfirmCSVImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	policy, ok := duplicatePolicy(w, r)
+	if !ok {
+		return
+	}
+	uploadID := mux.Vars(r)["upload_id"]
+
+	var payload CSVConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	delimiter := []rune(payload.Delimiter)
+	if len(delimiter) != 1 {
+		utils.HandleErrorResponse(w, "Delimiter must be a single character", http.StatusBadRequest)
+		return
+	}
+	if payload.Mapping.Question == payload.Mapping.Answer {
+		utils.HandleErrorResponse(w, "Question and answer must map to different columns", http.StatusBadRequest)
+		return
+	}
+	if payload.DeckID == 0 && payload.Mapping.Deck == nil {
+		utils.HandleErrorResponse(w, "A target deck or deck column is required", http.StatusBadRequest)
+		return
+	}
+	deckName := ""
+	if payload.DeckID != 0 {
+		if _, ok := requireDeckRole(w, r, payload.DeckID, types.RoleEditor); !ok {
+			return
+		}
+		if deckName, err = db.GetDeckName(payload.DeckID); err != nil {
+			utils.HandleErrorResponse(w, "Failed to retrieve deck", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	_, content, err := db.GetUpload(uploadID, userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve upload", http.StatusInternalServerError)
+		return
+	}
+	if content == nil {
+		utils.HandleErrorResponse(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+
+	rows, issues, err := importer.ReadCSV(content, delimiter[0], payload.HasHeader, payload.Mapping)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Could not read file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	decks := []types.ImportDeck{}
+	byName := map[string]int{}
+	for _, row := range rows {
+		card := types.ImportCard{Question: row.Question, Answer: row.Answer, Tags: utils.NormalizeTags(row.Tags)}
+		if row.Deck == "" {
+			if payload.DeckID == 0 {
+				issues = append(issues, types.ImportIssue{Item: fmt.Sprintf("row %d", row.Line), Reason: "no deck"})
+				continue
+			}
+			row.Deck = deckName
+		}
+		i, ok := byName[row.Deck]
+		if !ok {
+			i = len(decks)
+			byName[row.Deck] = i
+			deck := types.ImportDeck{Name: row.Deck}
+			if row.Deck == deckName {
+				deck.ID = payload.DeckID
+			}
+			decks = append(decks, deck)
+		}
+		decks[i].Cards = append(decks[i].Cards, card)
+	}
+
+	report, err := db.ImportDecks(userID, decks, policy, nil)
+	if errors.Is(err, dedupe.ErrTooManyCards) {
+		utils.HandleErrorResponse(w, tooManyForSimilarity, http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to import cards", http.StatusInternalServerError)
+		return
+	}
+	report.Skipped = append(report.Skipped, issues...)
+	if err := db.DeleteUpload(uploadID); err != nil {
+		log.Printf("Error removing imported upload: %v", err)
+	}
+
+	response := types.GCResponse[types.ImportReport]{
+		IsOK:    true,
+		Message: "Import Complete",
+		Payload: report,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ImportMarkdown imports a Markdown deck file (multipart field "file"). The
+// file updates the deck it was exported from, or ?deck_id when given, and
+// otherwise creates a new deck. Cards missing from the file are deleted when
+// ?prune=true.
+func ImportMarkdown(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	file, _, ok := readImportFile(w, r, maxCSVSize)
+	if !ok {
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+
+	deck, issues, err := importer.ReadMarkdown(content)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Could not read file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	deckID := deck.ID
+	if value := r.URL.Query().Get("deck_id"); value != "" {
+		if deckID, err = strconv.Atoi(value); err != nil {
+			utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+			return
+		}
+	}
+	if deckID != 0 {
+		if _, ok := requireDeckRole(w, r, deckID, types.RoleEditor); !ok {
+			return
+		}
+	} else if deck.Name == "" {
+		deck.Name = "Markdown Import"
+	}
+
+	report, err := db.SyncDeck(userID, deckID, deck.Name, deck.Cards, r.URL.Query().Get("prune") == "true", nil)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to import deck", http.StatusInternalServerError)
+		return
+	}
+	report.Skipped = append(report.Skipped, issues...)
+
+	response := types.GCResponse[types.SyncReport]{
+		IsOK:    true,
+		Message: "Import Complete",
+		Payload: report,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func generateUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
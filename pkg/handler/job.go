@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/exporter"
+	"go-flashcards-server/pkg/jobs"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+var jobImportFormats = map[string]bool{"apkg": true, "quizlet": true, "supermemo": true, "md": true}
+
+// EnqueueImport queues an import of an uploaded file (multipart field "file")
+// with ?format=apkg, quizlet, supermemo or md. Other query options are those
+// of the matching synchronous import endpoint.
+func EnqueueImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if !jobImportFormats[format] {
+		utils.HandleErrorResponse(w, "Format must be apkg, quizlet, supermemo or md", http.StatusBadRequest)
+		return
+	}
+	policy, ok := duplicatePolicy(w, r)
+	if !ok {
+		return
+	}
+
+	file, header, ok := readImportFile(w, r, maxImportSize)
+	if !ok {
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	deckID, deckName, ok := importTarget(w, r, header.Filename)
+	if !ok {
+		return
+	}
+
+	opts := importOptions(r, deckName)
+	params := types.JobParams{
+		Format:        format,
+		DeckID:        deckID,
+		DeckName:      deckName,
+		Filename:      header.Filename,
+		Scheduling:    opts.Scheduling,
+		Trim:          opts.Trim,
+		Prune:         r.URL.Query().Get("prune") == "true",
+		TermSeparator: opts.TermSeparator,
+		CardSeparator: opts.CardSeparator,
+		Duplicates:    policy,
+	}
+	enqueueJob(w, userID, types.JobImport, params, content)
+}
+
+type ExportJobRequest struct {
+	DeckID     int    `json:"deck_id"`
+	Format     string `json:"format"`
+	Scheduling bool   `json:"scheduling"`
+}
+
+// EnqueueExport queues an export of a deck. The file is downloaded from
+// GetJobArtifact once the job is done.
+func EnqueueExport(w http.ResponseWriter, r *http.Request) {
+	var payload ExportJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.Format == "" {
+		payload.Format = "json"
+	}
+	if _, ok := exporter.ContentType(payload.Format); !ok {
+		utils.HandleErrorResponse(w, "Format must be csv, json, apkg or md", http.StatusBadRequest)
+		return
+	}
+	userID, ok := requireDeckRole(w, r, payload.DeckID, types.RoleViewer)
+	if !ok {
+		return
+	}
+
+	params := types.JobParams{Format: payload.Format, DeckID: payload.DeckID, Scheduling: payload.Scheduling}
+	enqueueJob(w, userID, types.JobExport, params, nil)
+}
+
+func enqueueJob(w http.ResponseWriter, userID int, kind string, params types.JobParams, input []byte) {
+	jobID, err := jobs.Enqueue(userID, kind, params, input)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to queue job", http.StatusInternalServerError)
+		return
+	}
+	job, err := db.GetJob(jobID, userID)
+	if err != nil || job == nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve job", http.StatusInternalServerError)
+		return
+	}
+
+	response := types.GCResponse[types.Job]{
+		IsOK:    true,
+		Message: "Job queued",
+		Payload: job,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetJobs(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	list, err := db.GetJobsByUser(userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve jobs", http.StatusInternalServerError)
+		return
+	}
+
+	response := types.GCResponse[[]types.Job]{
+		IsOK:    true,
+		Message: "Jobs retrieved",
+		Payload: &list,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetJob reports a job's status and progress, and its result once done.
+func GetJob(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	job, err := db.GetJob(mux.Vars(r)["job_id"], userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve job", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		utils.HandleErrorResponse(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	response := types.GCResponse[types.Job]{
+		IsOK:    true,
+		Message: "Job retrieved",
+		Payload: job,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetJobArtifact downloads the file produced by a finished export job.
+func GetJobArtifact(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	name, contentType, content, err := db.GetJobArtifact(mux.Vars(r)["job_id"], userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve artifact", http.StatusInternalServerError)
+		return
+	}
+	if content == nil {
+		utils.HandleErrorResponse(w, "Artifact not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", attachment(name))
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
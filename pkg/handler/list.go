@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 500
+)
+
+var listSorts = map[string]bool{
+	types.SortPosition: true,
+	types.SortCreated:  true,
+	types.SortUpdated:  true,
+	types.SortDue:      true,
+	types.SortAlpha:    true,
+}
+
+// listFilter reads the paging and sorting options shared by list endpoints:
+// ?limit, ?cursor, ?sort=position|created|updated|due|alpha, ?order=asc|desc
+// and ?q.
+func listFilter(w http.ResponseWriter, r *http.Request) (types.ListFilter, bool) {
+	query := r.URL.Query()
+	filter := types.ListFilter{
+		Query:  strings.TrimSpace(query.Get("q")),
+		Sort:   query.Get("sort"),
+		Limit:  defaultListLimit,
+		Cursor: query.Get("cursor"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			utils.HandleErrorResponse(w, "Invalid limit", http.StatusBadRequest)
+			return filter, false
+		}
+		filter.Limit = min(n, maxListLimit)
+	}
+	if filter.Sort == "" {
+		filter.Sort = types.SortPosition
+	}
+	if !listSorts[filter.Sort] {
+		utils.HandleErrorResponse(w, "Sort must be position, created, updated, due or alpha", http.StatusBadRequest)
+		return filter, false
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		utils.HandleErrorResponse(w, "Order must be asc or desc", http.StatusBadRequest)
+		return filter, false
+	}
+	return filter, true
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/live"
+	"go-flashcards-server/pkg/middleware"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultQuestionSeconds = 20
+	minQuestionSeconds     = 5
+	maxQuestionSeconds     = 120
+	maxPlayerNameLength    = 32
+	// Joins are limited per client address, since players need no account.
+	joinWindow        = time.Minute
+	maxJoinsPerWindow = 20
+)
+
+var joins = joinLimiter{counts: map[string]int{}}
+
+// joinLimiter counts join attempts per address in fixed windows.
+type joinLimiter struct {
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+func (l *joinLimiter) allow(address string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.start) >= joinWindow {
+		l.start = now
+		clear(l.counts)
+	}
+	l.counts[address]++
+	return l.counts[address] <= maxJoinsPerWindow
+}
+
+// clientAddress is the remote IP of a request, without its port.
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || origin == middleware.AllowedOrigin
+	},
+}
+
+type LiveGamePayload struct {
+	PIN       string `json:"pin"`
+	DeckID    int    `json:"deck_id"`
+	DeckName  string `json:"deck_name"`
+	Questions int    `json:"questions"`
+	Seconds   int    `json:"seconds"`
+}
+
+// CreateLiveGame opens a lobby for a live quiz built from one of the caller's
+// decks. The host then connects to /live/{pin}/host to run it.
+func CreateLiveGame(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		DeckID  int `json:"deck_id"`
+		Seconds int `json:"seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.DeckID == 0 {
+		utils.HandleErrorResponse(w, "Deck is required", http.StatusBadRequest)
+		return
+	}
+	if payload.Seconds == 0 {
+		payload.Seconds = defaultQuestionSeconds
+	}
+	if payload.Seconds < minQuestionSeconds || payload.Seconds > maxQuestionSeconds {
+		utils.HandleErrorResponse(w, "Seconds must be between 5 and 120", http.StatusBadRequest)
+		return
+	}
+	userID, ok := requireDeckRole(w, r, payload.DeckID, types.RoleViewer)
+	if !ok {
+		return
+	}
+
+	deckName, err := db.GetDeckName(payload.DeckID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve deck", http.StatusInternalServerError)
+		return
+	}
+	cards, err := db.GetCardsByDeck(payload.DeckID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error retrieving cards", http.StatusInternalServerError)
+		return
+	}
+	liveCards := make([]live.Card, len(cards))
+	for i, card := range cards {
+		liveCards[i] = live.Card{Question: card.Question, Answer: card.Answer}
+	}
+
+	game, err := live.NewGame(userID, deckName, liveCards, time.Duration(payload.Seconds)*time.Second)
+	if errors.Is(err, live.ErrNotEnoughCards) {
+		utils.HandleErrorResponse(w, "Deck needs at least two cards with different answers", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to create game", http.StatusInternalServerError)
+		return
+	}
+
+	response := types.GCResponse[LiveGamePayload]{
+		IsOK:    true,
+		Message: "Game Created",
+		Payload: &LiveGamePayload{
+			PIN:       game.PIN,
+			DeckID:    payload.DeckID,
+			DeckName:  deckName,
+			Questions: len(cards),
+			Seconds:   payload.Seconds,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// HostLiveGame upgrades the host's connection. The host sends "start" and
+// "next" to advance questions and "end" to stop the game.
+func HostLiveGame(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	game := live.Find(mux.Vars(r)["pin"])
+	if game == nil || game.HostID != userID {
+		utils.HandleErrorResponse(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Error upgrading host connection: %v", err)
+		return
+	}
+	if err := game.ServeHost(conn); err != nil {
+		log.Printf("Host rejected from game %s: %v", game.PIN, err)
+	}
+}
+
+// JoinLiveGame upgrades a player's connection. Players need no account, only
+// the game PIN and a display name passed as ?name=. Joins are refused once the
+// game has started or is full, and are rate limited per address.
+func JoinLiveGame(w http.ResponseWriter, r *http.Request) {
+	if !joins.allow(clientAddress(r), time.Now()) {
+		utils.HandleErrorResponse(w, "Too many join attempts", http.StatusTooManyRequests)
+		return
+	}
+	game := live.Find(mux.Vars(r)["pin"])
+	if game == nil {
+		utils.HandleErrorResponse(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" || len(name) > maxPlayerNameLength {
+		utils.HandleErrorResponse(w, "Name is required and must be at most 32 characters", http.StatusBadRequest)
+		return
+	}
+	switch err := game.CanJoin(name); err {
+	case live.ErrGameFinished:
+		utils.HandleErrorResponse(w, "Game has finished", http.StatusConflict)
+		return
+	case live.ErrGameStarted:
+		utils.HandleErrorResponse(w, "Game has already started", http.StatusConflict)
+		return
+	case live.ErrGameFull:
+		utils.HandleErrorResponse(w, "Game is full", http.StatusConflict)
+		return
+	case live.ErrNameTaken:
+		utils.HandleErrorResponse(w, "Name already taken", http.StatusConflict)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Error upgrading player connection: %v", err)
+		return
+	}
+	if err := game.ServePlayer(conn, name); err != nil {
+		log.Printf("Player %q rejected from game %s: %v", name, game.PIN, err)
+	}
+}
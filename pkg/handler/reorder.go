@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ReorderRequest either moves one item next to another, with id and exactly
+// one of before or after, or sets the whole order with ids.
+type ReorderRequest struct {
+	ID     int   `json:"id"`
+	Before int   `json:"before"`
+	After  int   `json:"after"`
+	IDs    []int `json:"ids"`
+}
+
+// valid reports whether the request is exactly one of the two forms.
+func (p ReorderRequest) valid() bool {
+	if len(p.IDs) > 0 {
+		return p.ID == 0 && p.Before == 0 && p.After == 0
+	}
+	return p.ID != 0 && (p.Before == 0) != (p.After == 0)
+}
+
+// anchor returns the item to move next to and whether to go after it.
+func (p ReorderRequest) anchor() (int, bool) {
+	if p.After != 0 {
+		return p.After, true
+	}
+	return p.Before, false
+}
+
+func decodeReorder(w http.ResponseWriter, r *http.Request) (ReorderRequest, bool) {
+	var payload ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return payload, false
+	}
+	if !payload.valid() {
+		utils.HandleErrorResponse(w, "Either ids, or id with before or after, is required", http.StatusBadRequest)
+		return payload, false
+	}
+	return payload, true
+}
+
+// ReorderDecks changes the order of the caller's own decks.
+func ReorderDecks(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	payload, ok := decodeReorder(w, r)
+	if !ok {
+		return
+	}
+
+	var positions []types.ItemPosition
+	if len(payload.IDs) > 0 {
+		positions, err = db.ReorderDecks(userID, payload.IDs)
+	} else {
+		anchorID, after := payload.anchor()
+		positions, err = db.MoveDeck(userID, payload.ID, anchorID, after)
+	}
+	writeReorder(w, positions, err, "Decks must be your own and, with ids, list each of them once")
+}
+
+// ReorderCards changes the order of the cards of a deck the caller can edit.
+func ReorderCards(w http.ResponseWriter, r *http.Request) {
+	deckID, err := strconv.Atoi(mux.Vars(r)["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireDeckRole(w, r, deckID, types.RoleEditor); !ok {
+		return
+	}
+	payload, ok := decodeReorder(w, r)
+	if !ok {
+		return
+	}
+
+	var positions []types.ItemPosition
+	if len(payload.IDs) > 0 {
+		positions, err = db.ReorderCards(deckID, payload.IDs)
+	} else {
+		anchorID, after := payload.anchor()
+		positions, err = db.MoveCardPosition(deckID, payload.ID, anchorID, after)
+	}
+	writeReorder(w, positions, err, "Cards must belong to the deck and, with ids, list each of them once")
+}
+
+func writeReorder(w http.ResponseWriter, positions []types.ItemPosition, err error, mismatch string) {
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to reorder", http.StatusInternalServerError)
+		return
+	}
+	if positions == nil {
+		utils.HandleErrorResponse(w, mismatch, http.StatusBadRequest)
+		return
+	}
+
+	response := types.GCResponse[[]types.ItemPosition]{
+		IsOK:    true,
+		Message: "Order updated",
+		Payload: &positions,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
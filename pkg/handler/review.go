@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/srs"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ReviewCard records a graded review against the caller's own scheduling
+// state, so users sharing a deck each keep an independent schedule.
+func ReviewCard(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	cardID, err := strconv.Atoi(params["card_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid card id", http.StatusBadRequest)
+		return
+	}
+	userID, ok := requireCardRole(w, r, cardID, types.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Grade *int `json:"grade"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.Grade == nil || *payload.Grade < srs.MinGrade || *payload.Grade > srs.MaxGrade {
+		utils.HandleErrorResponse(w, "Grade must be between 0 and 5", http.StatusBadRequest)
+		return
+	}
+
+	state, err := db.RecordReview(userID, cardID, *payload.Grade)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to record review", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[types.CardState]{
+		IsOK:    true,
+		Message: "Review recorded",
+		Payload: state,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetCardRevisions lists a card's edits, newest first, with a line diff of
+// the question and answer of each.
+func GetCardRevisions(w http.ResponseWriter, r *http.Request) {
+	cardID, err := strconv.Atoi(mux.Vars(r)["card_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid card id", http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireCardRole(w, r, cardID, types.RoleViewer); !ok {
+		return
+	}
+
+	revisions, err := db.GetCardRevisions(cardID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve revisions", http.StatusInternalServerError)
+		return
+	}
+	for i := range revisions {
+		rev := &revisions[i]
+		rev.QuestionDiff = utils.DiffLines(rev.QuestionBefore, rev.QuestionAfter)
+		rev.AnswerDiff = utils.DiffLines(rev.AnswerBefore, rev.AnswerAfter)
+	}
+
+	response := types.GCResponse[[]types.CardRevision]{
+		IsOK:    true,
+		Message: "Revisions retrieved",
+		Payload: &revisions,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevertCard undoes revision rev and any later edits, restoring the text the
+// card had before rev. The revert shows up as a new revision.
+func RevertCard(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	cardID, err := strconv.Atoi(params["card_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid card id", http.StatusBadRequest)
+		return
+	}
+	rev, err := strconv.Atoi(params["rev"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+	userID, ok := requireCardRole(w, r, cardID, types.RoleEditor)
+	if !ok {
+		return
+	}
+
+	card, err := db.RevertCard(cardID, userID, rev)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to revert card", http.StatusInternalServerError)
+		return
+	}
+	if card == nil {
+		utils.HandleErrorResponse(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+
+	response := types.GCResponse[types.Card]{
+		IsOK:    true,
+		Message: fmt.Sprintf("Card %d reverted to before revision %d", cardID, rev),
+		Payload: card,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
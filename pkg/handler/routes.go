@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"go-flashcards-server/pkg/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterDeckRoutes adds the /deck and /card routes to r, behind
+// authentication and the access check against the current store. Every route
+// naming a {deck_id} or {card_id} belongs here so that check covers it.
+func RegisterDeckRoutes(r *mux.Router) {
+	deckRouter := r.PathPrefix("/deck").Subrouter()
+	deckRouter.Use(middleware.AuthMiddleware)
+	deckRouter.Use(middleware.AccessMiddleware(store))
+	deckRouter.HandleFunc("/create", CreateDeck).Methods("POST")
+	deckRouter.HandleFunc("", GetDecks).Methods("GET", "OPTIONS")
+	deckRouter.HandleFunc("/reorder", ReorderDecks).Methods("POST")
+	deckRouter.HandleFunc("/update/{deck_id}", UpdateDeck).Methods("PUT")
+	deckRouter.HandleFunc("/delete/{deck_id}", DeleteDeck).Methods("DELETE")
+	deckRouter.HandleFunc("/share/{deck_id}", ShareDeck).Methods("POST")
+	deckRouter.HandleFunc("/share/{deck_id}", GetDeckShares).Methods("GET")
+	deckRouter.HandleFunc("/share/{deck_id}/{user_id}", UnshareDeck).Methods("DELETE")
+	deckRouter.HandleFunc("/publish/{deck_id}", PublishDeck).Methods("POST")
+	deckRouter.HandleFunc("/publish/{deck_id}", UnpublishDeck).Methods("DELETE")
+	deckRouter.HandleFunc("/clone/{deck_id}", CloneDeck).Methods("POST")
+	deckRouter.HandleFunc("/{deck_id}/upstream", GetUpstream).Methods("GET")
+	deckRouter.HandleFunc("/{deck_id}/upstream", ApplyUpstream).Methods("POST")
+	deckRouter.HandleFunc("/{deck_id}/export", ExportDeck).Methods("GET")
+	deckRouter.HandleFunc("/{deck_id}/duplicates", GetDeckDuplicates).Methods("GET")
+
+	cardRouter := r.PathPrefix("/card").Subrouter()
+	cardRouter.Use(middleware.AuthMiddleware)
+	cardRouter.Use(middleware.AccessMiddleware(store))
+	cardRouter.HandleFunc("/create", CreateCard).Methods("POST")
+	cardRouter.HandleFunc("/bulk", BulkCards).Methods("POST")
+	cardRouter.HandleFunc("/move", MoveCards).Methods("POST")
+	cardRouter.HandleFunc("/copy", CopyCards).Methods("POST")
+	cardRouter.HandleFunc("/reorder/{deck_id}", ReorderCards).Methods("POST")
+	cardRouter.HandleFunc("/{deck_id}", GetCardsByDeck).Methods("GET")
+	cardRouter.HandleFunc("/update/{card_id}", UpdateCard).Methods("PUT")
+	cardRouter.HandleFunc("/delete/{card_id}", DeleteCard).Methods("DELETE")
+	cardRouter.HandleFunc("/review/{card_id}", ReviewCard).Methods("POST")
+	cardRouter.HandleFunc("/{card_id}/revisions", GetCardRevisions).Methods("GET")
+	cardRouter.HandleFunc("/{card_id}/revert/{rev}", RevertCard).Methods("POST")
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type ShareRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+func ShareDeck(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	deckID, err := strconv.Atoi(params["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return
+	}
+	ownerID, ok := requireDeckRole(w, r, deckID, types.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var payload ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.Email == "" {
+		utils.HandleErrorResponse(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+	if payload.Role != types.RoleViewer && payload.Role != types.RoleEditor {
+		utils.HandleErrorResponse(w, "Role must be viewer or editor", http.StatusBadRequest)
+		return
+	}
+
+	user, err := store.GetUserByEmail(payload.Email)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to share deck", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		utils.HandleErrorResponse(w, "User not found", http.StatusNotFound)
+		return
+	}
+	userID := user.ID
+	if userID == ownerID {
+		utils.HandleErrorResponse(w, "Cannot share a deck with its owner", http.StatusBadRequest)
+		return
+	}
+
+	if err := store.ShareDeck(deckID, userID, payload.Role); err != nil {
+		utils.HandleErrorResponse(w, "Failed to share deck", http.StatusInternalServerError)
+		return
+	}
+
+	response := types.GCResponse[types.DeckShare]{
+		IsOK:    true,
+		Message: "Deck Shared",
+		Payload: &types.DeckShare{
+			DeckID: deckID,
+			UserID: userID,
+			Email:  payload.Email,
+			Role:   payload.Role,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetDeckShares(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	deckID, err := strconv.Atoi(params["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireDeckRole(w, r, deckID, types.RoleOwner); !ok {
+		return
+	}
+
+	shares, err := store.GetDeckShares(deckID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve shares", http.StatusInternalServerError)
+		return
+	}
+	response := types.GCResponse[[]types.DeckShare]{
+		IsOK:    true,
+		Message: "Shares retrieved",
+		Payload: &shares,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func UnshareDeck(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	deckID, err := strconv.Atoi(params["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck id", http.StatusBadRequest)
+		return
+	}
+	userID, err := strconv.Atoi(params["user_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireDeckRole(w, r, deckID, types.RoleOwner); !ok {
+		return
+	}
+
+	rowsAffected, err := store.UnshareDeck(deckID, userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to unshare deck", http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected == 0 {
+		utils.HandleErrorResponse(w, "Share not found", http.StatusNotFound)
+		return
+	}
+
+	message := fmt.Sprintf("Deck %d no longer shared with user %d", deckID, userID)
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,12 @@
+package handler
+
+import "go-flashcards-server/pkg/db"
+
+// store backs the user, deck and card handlers and the access checks.
+var store db.Store = db.SQLStore{}
+
+// UseStore replaces the storage behind the handlers, e.g. with a
+// db.MemoryStore in tests.
+func UseStore(s db.Store) {
+	store = s
+}
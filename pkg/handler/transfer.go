@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"net/http"
+)
+
+type CardTransferRequest struct {
+	CardIDs    []int `json:"card_ids"`
+	DeckID     int   `json:"deck_id"`
+	ResetState bool  `json:"reset_state"`
+}
+
+// MoveCards moves cards the caller can edit into a deck they can edit,
+// keeping review state unless reset_state is set.
+func MoveCards(w http.ResponseWriter, r *http.Request) {
+	transferCards(w, r, types.RoleEditor, "Cards moved", db.MoveCards)
+}
+
+// CopyCards copies cards the caller can view into a deck they can edit,
+// copying their review state unless reset_state is set.
+func CopyCards(w http.ResponseWriter, r *http.Request) {
+	transferCards(w, r, types.RoleViewer, "Cards copied", db.CopyCards)
+}
+
+func transferCards(w http.ResponseWriter, r *http.Request, sourceRole, message string,
+	transfer func(userID int, cardIDs []int, deckID int, resetState bool) ([]types.CardTransfer, error)) {
+	var payload CardTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.HandleErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if payload.DeckID == 0 || len(payload.CardIDs) == 0 {
+		utils.HandleErrorResponse(w, "Deck and cards are required", http.StatusBadRequest)
+		return
+	}
+	if len(payload.CardIDs) > maxBulkOperations {
+		utils.HandleErrorResponse(w, fmt.Sprintf("At most %d cards are allowed", maxBulkOperations), http.StatusBadRequest)
+		return
+	}
+	userID, ok := requireDeckRole(w, r, payload.DeckID, types.RoleEditor)
+	if !ok {
+		return
+	}
+
+	seen := map[int]bool{}
+	cardIDs := []int{}
+	for _, cardID := range payload.CardIDs {
+		if seen[cardID] {
+			continue
+		}
+		seen[cardID] = true
+		if _, ok := requireCardRole(w, r, cardID, sourceRole); !ok {
+			return
+		}
+		cardIDs = append(cardIDs, cardID)
+	}
+
+	transferred, err := transfer(userID, cardIDs, payload.DeckID, payload.ResetState)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to transfer cards", http.StatusInternalServerError)
+		return
+	}
+
+	response := types.GCResponse[[]types.CardTransfer]{
+		IsOK:    true,
+		Message: message,
+		Payload: &transferred,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
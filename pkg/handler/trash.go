@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-flashcards-server/pkg/config"
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetTrash lists the caller's deleted decks and the deleted cards of decks
+// they can edit, with the time each will be purged.
+func GetTrash(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	trash, err := db.GetTrash(userID, config.TrashRetentionDays)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve trash", http.StatusInternalServerError)
+		return
+	}
+
+	response := types.GCResponse[types.Trash]{
+		IsOK:    true,
+		Message: "Trash retrieved",
+		Payload: trash,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RestoreDeck takes a deck the caller owns out of the trash.
+func RestoreDeck(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIdFromContext(r)
+	if err != nil {
+		log.Println("Error getting user: ", err.Error())
+		utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	deckID, err := strconv.Atoi(mux.Vars(r)["deck_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid deck ID", http.StatusBadRequest)
+		return
+	}
+	ownerID, err := db.GetTrashedDeckOwner(deckID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve deck", http.StatusInternalServerError)
+		return
+	}
+	if ownerID != userID {
+		utils.HandleErrorResponse(w, "Deck not found in trash", http.StatusNotFound)
+		return
+	}
+
+	if _, err := db.RestoreDeck(deckID); err != nil {
+		utils.HandleErrorResponse(w, "Failed to restore deck", http.StatusInternalServerError)
+		return
+	}
+	writeRestored(w, fmt.Sprintf("Deck %d restored", deckID))
+}
+
+// RestoreCard takes a card out of the trash. The caller must be able to edit
+// its deck, which must not be in the trash itself.
+func RestoreCard(w http.ResponseWriter, r *http.Request) {
+	cardID, err := strconv.Atoi(mux.Vars(r)["card_id"])
+	if err != nil {
+		utils.HandleErrorResponse(w, "Invalid card id", http.StatusBadRequest)
+		return
+	}
+	deckID, err := db.GetTrashedCardDeckID(cardID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Failed to retrieve card", http.StatusInternalServerError)
+		return
+	}
+	if deckID == 0 {
+		utils.HandleErrorResponse(w, "Card not found in trash", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkDeckRole(w, r, deckID, types.RoleEditor, "Card not found in trash"); !ok {
+		return
+	}
+
+	if _, err := db.RestoreCard(cardID); err != nil {
+		utils.HandleErrorResponse(w, "Failed to restore card", http.StatusInternalServerError)
+		return
+	}
+	writeRestored(w, fmt.Sprintf("Card %d restored", cardID))
+}
+
+func writeRestored(w http.ResponseWriter, message string) {
+	response := types.GCResponse[string]{
+		IsOK:    true,
+		Message: message,
+		Payload: nil,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
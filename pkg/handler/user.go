@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"go-flashcards-server/pkg/config"
-	"go-flashcards-server/pkg/db"
 	"go-flashcards-server/pkg/types"
 	"go-flashcards-server/pkg/utils"
 
@@ -67,8 +66,13 @@ func SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = db.DB.Exec("INSERT INTO user (first_name, last_name, email, password_hash, salt) VALUES (?, ?, ?, ?, ?)",
-		user.FirstName, user.LastName, user.Email, hashedPassword, salt)
+	_, err = store.CreateUser(types.User{
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		Email:        user.Email,
+		PasswordHash: string(hashedPassword),
+		Salt:         salt,
+	})
 	if err != nil {
 		log.Printf("Error creating user: %v\n", err)
 		utils.HandleErrorResponse(w, "Error creating user", http.StatusInternalServerError)
@@ -115,19 +119,14 @@ func Login(w http.ResponseWriter, r *http.Request) {
 }
 
 func loginUser(creds *Credentials, w http.ResponseWriter) (*UserPayload, error) {
-	var user User
-	var storedHashedPassword string
-	err := db.DB.QueryRow(
-		"SELECT id, first_name, last_name, email, password_hash, salt FROM user WHERE email = ?",
-		creds.Email,
-	).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &storedHashedPassword, &user.Salt)
-	if err != nil {
-		fmt.Printf("Error retrieving user: %v\n", err.Error())
+	user, err := store.GetUserByEmail(creds.Email)
+	if err != nil || user == nil {
+		fmt.Printf("Error retrieving user: %s\n", creds.Email)
 		return nil, errors.New("Error retrieving user")
 	}
 
 	passwordWithSalt := creds.Password + user.Salt
-	err = bcrypt.CompareHashAndPassword([]byte(storedHashedPassword), []byte(passwordWithSalt))
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(passwordWithSalt))
 	if err != nil {
 		log.Printf("Failed login attempt for user: %s", creds.Email)
 		return nil, errors.New("Failed login attempt")
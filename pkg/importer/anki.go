@@ -0,0 +1,328 @@
+package importer
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-flashcards-server/pkg/srs"
+	"go-flashcards-server/pkg/types"
+
+	_ "modernc.org/sqlite"
+)
+
+var (
+	ErrNoCollection          = errors.New("package does not contain an Anki collection")
+	ErrUnsupportedCollection = errors.New("collection format is not supported; export with \"Support older Anki versions\" enabled")
+	ErrCollectionTooLarge    = errors.New("collection is too large")
+)
+
+const (
+	ankiFieldSeparator = "\x1f"
+	ankiModelCloze     = 1
+	ankiCardNew        = 0
+	ankiCardReview     = 2
+
+	// Queues say where a card is now, and with it what its due means.
+	ankiQueueSuspended   = -1
+	ankiQueueNew         = 0
+	ankiQueueReview      = 2
+	ankiQueueDayLearning = 3
+	// ankiTimestampDue tells Unix timestamps from day numbers for buried
+	// cards, the way Anki does when it unburies them.
+	ankiTimestampDue = 1_000_000_000
+
+	// maxCollectionSize caps the extracted collection, whatever the package
+	// claims, so a small package cannot fill the disk.
+	maxCollectionSize = 1 << 30
+)
+
+var (
+	lineBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</div>|</p>|</li>`)
+	tagPattern       = regexp.MustCompile(`<[^>]*>`)
+	mediaPattern     = regexp.MustCompile(`(?i)<img[^>]*>|\[sound:[^\]]*\]`)
+	clozePattern     = regexp.MustCompile(`\{\{c\d+::(.*?)(?:::(.*?))?\}\}`)
+	blankLines       = regexp.MustCompile(`\n{3,}`)
+)
+
+type ankiDeck struct {
+	Name string `json:"name"`
+}
+
+type ankiModel struct {
+	Name string `json:"name"`
+	Type int    `json:"type"`
+}
+
+// AnkiImporter reads .apkg packages.
+type AnkiImporter struct {
+	WithScheduling bool
+}
+
+func (a AnkiImporter) Import(r io.ReaderAt, size int64) (*Result, error) {
+	return ReadAnkiPackage(r, size, a.WithScheduling)
+}
+
+// ReadAnkiPackage reads the decks and notes of an Anki package. Each note
+// becomes one card, placed in the deck of its first card. When withScheduling
+// is set, review state of that first card is carried over.
+func ReadAnkiPackage(r io.ReaderAt, size int64, withScheduling bool) (*Result, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("reading package: %w", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	collection := files["collection.anki21"]
+	if collection == nil {
+		collection = files["collection.anki2"]
+	}
+	if collection == nil {
+		if files["collection.anki21b"] != nil {
+			return nil, ErrUnsupportedCollection
+		}
+		return nil, ErrNoCollection
+	}
+
+	path, err := extractToTemp(collection)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	conn, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("opening collection: %w", err)
+	}
+	defer conn.Close()
+
+	pkg, err := readAnkiCollection(conn, withScheduling)
+	if err != nil {
+		return nil, err
+	}
+	pkg.MediaSkipped += countAnkiMedia(files)
+	return pkg, nil
+}
+
+func extractToTemp(f *zip.File) (string, error) {
+	if f.UncompressedSize64 > maxCollectionSize {
+		return "", ErrCollectionTooLarge
+	}
+	src, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("opening collection: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "anki-*.sqlite")
+	if err != nil {
+		return "", fmt.Errorf("extracting collection: %w", err)
+	}
+	defer dst.Close()
+	n, err := io.Copy(dst, io.LimitReader(src, maxCollectionSize+1))
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", fmt.Errorf("extracting collection: %w", err)
+	}
+	if n > maxCollectionSize {
+		os.Remove(dst.Name())
+		return "", ErrCollectionTooLarge
+	}
+	return dst.Name(), nil
+}
+
+// countAnkiMedia counts the media files bundled with the package, which are
+// not imported.
+func countAnkiMedia(files map[string]*zip.File) int {
+	count := 0
+	for name := range files {
+		if _, err := strconv.Atoi(name); err == nil {
+			count++
+		}
+	}
+	return count
+}
+
+func readAnkiCollection(conn *sql.DB, withScheduling bool) (*Result, error) {
+	var created int64
+	var decksJSON, modelsJSON string
+	if err := conn.QueryRow("SELECT crt, decks, models FROM col").Scan(&created, &decksJSON, &modelsJSON); err != nil {
+		return nil, fmt.Errorf("reading collection: %w", err)
+	}
+	decks := map[string]ankiDeck{}
+	models := map[string]ankiModel{}
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		return nil, fmt.Errorf("reading decks: %w", err)
+	}
+	if err := json.Unmarshal([]byte(modelsJSON), &models); err != nil {
+		return nil, fmt.Errorf("reading note types: %w", err)
+	}
+	if len(models) == 0 {
+		return nil, ErrUnsupportedCollection
+	}
+
+	rows, err := conn.Query(`SELECT c.nid, c.did, c.type, c.queue, c.due, c.ivl, c.factor, c.reps, c.lapses, n.mid, n.flds
+		FROM cards c JOIN notes n ON n.id = c.nid
+		ORDER BY c.nid, c.ord`)
+	if err != nil {
+		return nil, fmt.Errorf("reading cards: %w", err)
+	}
+	defer rows.Close()
+
+	pkg := &Result{Skipped: []types.ImportIssue{}}
+	byDeck := map[string]*types.ImportDeck{}
+	lastNote := int64(0)
+	for rows.Next() {
+		var noteID, deckID, modelID, due int64
+		var cardType, queue, interval, factor, reps, lapses int
+		var fields string
+		if err := rows.Scan(&noteID, &deckID, &cardType, &queue, &due, &interval, &factor, &reps, &lapses, &modelID, &fields); err != nil {
+			return nil, fmt.Errorf("reading cards: %w", err)
+		}
+		// Notes with several cards (reversed or cloze) are imported once.
+		if noteID == lastNote {
+			continue
+		}
+		lastNote = noteID
+		item := fmt.Sprintf("note %d", noteID)
+		if queue == ankiQueueSuspended {
+			pkg.Skipped = append(pkg.Skipped, types.ImportIssue{Item: item, Reason: "card is suspended"})
+			continue
+		}
+
+		model, ok := models[strconv.FormatInt(modelID, 10)]
+		if !ok {
+			pkg.Skipped = append(pkg.Skipped, types.ImportIssue{Item: item, Reason: "unknown note type"})
+			continue
+		}
+		if mediaPattern.MatchString(fields) {
+			pkg.Skipped = append(pkg.Skipped, types.ImportIssue{Item: item, Reason: "media references removed"})
+		}
+		question, answer, reason := ankiNoteText(model, strings.Split(fields, ankiFieldSeparator))
+		if reason != "" {
+			pkg.Skipped = append(pkg.Skipped, types.ImportIssue{Item: item, Reason: reason})
+			continue
+		}
+
+		deckName := "Anki Import"
+		if deck, ok := decks[strconv.FormatInt(deckID, 10)]; ok && deck.Name != "" {
+			deckName = deck.Name
+		}
+		deck := byDeck[deckName]
+		if deck == nil {
+			deck = &types.ImportDeck{Name: deckName}
+			byDeck[deckName] = deck
+		}
+		card := types.ImportCard{Question: question, Answer: answer}
+		if withScheduling {
+			card.State = ankiState(created, cardType, queue, due, interval, factor, reps, lapses)
+		}
+		deck.Cards = append(deck.Cards, card)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading cards: %w", err)
+	}
+
+	names := make([]string, 0, len(byDeck))
+	for name := range byDeck {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pkg.Decks = append(pkg.Decks, *byDeck[name])
+	}
+	return pkg, nil
+}
+
+// ankiNoteText maps note fields to a question and answer. The first field is
+// the question and the remaining non-empty fields form the answer; cloze notes
+// blank their deletions in the question and reveal them in the answer.
+func ankiNoteText(model ankiModel, fields []string) (string, string, string) {
+	if model.Type == ankiModelCloze {
+		text := fields[0]
+		question := clozePattern.ReplaceAllStringFunc(text, func(m string) string {
+			hint := clozePattern.FindStringSubmatch(m)[2]
+			if hint != "" {
+				return "[" + hint + "]"
+			}
+			return "[...]"
+		})
+		answer := clozePattern.ReplaceAllString(text, "$1")
+		if len(fields) > 1 && htmlToText(fields[1]) != "" {
+			answer += "\n\n" + fields[1]
+		}
+		question, answer = htmlToText(question), htmlToText(answer)
+		if question == "" {
+			return "", "", "empty cloze text"
+		}
+		return question, answer, ""
+	}
+
+	if len(fields) < 2 {
+		return "", "", "note has a single field"
+	}
+	question := htmlToText(fields[0])
+	var parts []string
+	for _, field := range fields[1:] {
+		if text := htmlToText(field); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	answer := strings.Join(parts, "\n\n")
+	if question == "" || answer == "" {
+		return "", "", "empty question or answer"
+	}
+	return question, answer, ""
+}
+
+// ankiState converts Anki scheduling of a card into our review state. New
+// cards have no state. Review and interday learning cards are due in days
+// since the collection was created; other learning cards are due at a Unix
+// timestamp. Buried cards keep their due and are read by their type.
+func ankiState(created int64, cardType, queue int, due int64, interval, factor, reps, lapses int) *types.ImportState {
+	if cardType == ankiCardNew || queue == ankiQueueNew {
+		return nil
+	}
+	state := &types.ImportState{
+		IntervalDays: max(interval, 0),
+		Ease:         float64(factor) / 1000,
+		Reps:         reps,
+		Lapses:       lapses,
+	}
+	if state.Ease == 0 {
+		state.Ease = srs.DefaultEase
+	}
+	inDays := queue == ankiQueueReview || queue == ankiQueueDayLearning
+	if queue < 0 {
+		inDays = cardType == ankiCardReview || due < ankiTimestampDue
+	}
+	if inDays {
+		state.DueAt = time.Unix(created, 0).UTC().AddDate(0, 0, int(due))
+	} else {
+		state.DueAt = time.Unix(due, 0).UTC()
+	}
+	return state
+}
+
+func htmlToText(s string) string {
+	s = lineBreakPattern.ReplaceAllString(s, "\n")
+	s = mediaPattern.ReplaceAllString(s, "")
+	s = tagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	s = blankLines.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,231 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"go-flashcards-server/pkg/types"
+)
+
+const (
+	csvSampleRows = 5
+	csvDetectRows = 20
+)
+
+const (
+	ColumnQuestion = "question"
+	ColumnAnswer   = "answer"
+	ColumnTags     = "tags"
+	ColumnDeck     = "deck"
+	ColumnIgnore   = "ignore"
+)
+
+var ErrEmptyFile = errors.New("file has no rows")
+
+var csvDelimiters = []rune{',', '\t', ';', '|'}
+
+// headerNames maps common column titles to the card field they hold.
+var headerNames = map[string]string{
+	"question":   ColumnQuestion,
+	"front":      ColumnQuestion,
+	"term":       ColumnQuestion,
+	"prompt":     ColumnQuestion,
+	"answer":     ColumnAnswer,
+	"back":       ColumnAnswer,
+	"definition": ColumnAnswer,
+	"tags":       ColumnTags,
+	"tag":        ColumnTags,
+	"deck":       ColumnDeck,
+}
+
+// CSVRow is one data row of a delimited file mapped to card fields.
+type CSVRow struct {
+	Line     int
+	Question string
+	Answer   string
+	Tags     []string
+	Deck     string
+}
+
+// DetectDelimiter picks the candidate delimiter that splits the first rows
+// into the most columns with a consistent column count.
+func DetectDelimiter(data []byte) rune {
+	best, bestScore := ',', 0
+	for _, delimiter := range csvDelimiters {
+		records, err := readRecords(data, delimiter, csvDetectRows)
+		if err != nil || len(records) == 0 {
+			continue
+		}
+		width := len(records[0])
+		consistent := 0
+		for _, record := range records {
+			if len(record) == width {
+				consistent++
+			}
+		}
+		if width < 2 {
+			continue
+		}
+		if score := consistent * width; score > bestScore {
+			best, bestScore = delimiter, score
+		}
+	}
+	return best
+}
+
+// PreviewCSV detects the delimiter and header of a file and guesses which
+// column holds which card field.
+func PreviewCSV(data []byte) (*types.CSVPreview, error) {
+	data = trimBOM(data)
+	delimiter := DetectDelimiter(data)
+	records, err := readRecords(data, delimiter, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrEmptyFile
+	}
+
+	preview := &types.CSVPreview{Delimiter: string(delimiter), Columns: []types.CSVColumn{}}
+	guesses := map[int]string{}
+	for i, cell := range records[0] {
+		if field, ok := headerNames[strings.ToLower(strings.TrimSpace(cell))]; ok {
+			guesses[i] = field
+			preview.HasHeader = true
+		}
+	}
+	body := records
+	if preview.HasHeader {
+		body = records[1:]
+	} else {
+		guesses = map[int]string{0: ColumnQuestion, 1: ColumnAnswer}
+	}
+	for _, record := range body {
+		if !isBlankRecord(record) {
+			preview.Rows++
+		}
+	}
+
+	width := 0
+	for _, record := range records {
+		width = max(width, len(record))
+	}
+	for i := 0; i < width; i++ {
+		column := types.CSVColumn{Index: i, Samples: []string{}, Guess: ColumnIgnore}
+		if preview.HasHeader && i < len(records[0]) {
+			column.Header = strings.TrimSpace(records[0][i])
+		}
+		if guess, ok := guesses[i]; ok {
+			column.Guess = guess
+		}
+		for _, record := range body[:min(len(body), csvSampleRows)] {
+			if i < len(record) {
+				column.Samples = append(column.Samples, record[i])
+			}
+		}
+		preview.Columns = append(preview.Columns, column)
+	}
+	return preview, nil
+}
+
+// ReadCSV maps every data row to card fields. Rows that cannot be used are
+// returned as issues rather than failing the whole file.
+func ReadCSV(data []byte, delimiter rune, hasHeader bool, mapping types.CSVMapping) ([]CSVRow, []types.ImportIssue, error) {
+	records, err := readRecords(trimBOM(data), delimiter, -1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := []CSVRow{}
+	issues := []types.ImportIssue{}
+	for i, record := range records {
+		if i == 0 && hasHeader {
+			continue
+		}
+		line := i + 1
+		item := fmt.Sprintf("row %d", line)
+		if isBlankRecord(record) {
+			continue
+		}
+
+		cell := func(index int) (string, bool) {
+			if index < 0 || index >= len(record) {
+				return "", false
+			}
+			return strings.TrimSpace(record[index]), true
+		}
+		question, ok := cell(mapping.Question)
+		if !ok {
+			issues = append(issues, types.ImportIssue{Item: item, Reason: "missing question column"})
+			continue
+		}
+		answer, ok := cell(mapping.Answer)
+		if !ok {
+			issues = append(issues, types.ImportIssue{Item: item, Reason: "missing answer column"})
+			continue
+		}
+		if question == "" || answer == "" {
+			issues = append(issues, types.ImportIssue{Item: item, Reason: "empty question or answer"})
+			continue
+		}
+
+		row := CSVRow{Line: line, Question: question, Answer: answer}
+		if mapping.Tags != nil {
+			if tags, ok := cell(*mapping.Tags); ok {
+				row.Tags = SplitTags(tags)
+			}
+		}
+		if mapping.Deck != nil {
+			row.Deck, _ = cell(*mapping.Deck)
+		}
+		rows = append(rows, row)
+	}
+	return rows, issues, nil
+}
+
+// SplitTags splits a tag cell on whitespace, commas and semicolons.
+func SplitTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ',' || r == ';'
+	})
+}
+
+// readRecords parses up to limit records (all when limit is negative),
+// tolerating rows of differing width and stray quotes.
+func readRecords(data []byte, delimiter rune, limit int) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records := [][]string{}
+	for limit < 0 || len(records) < limit {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing file: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func trimBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+}
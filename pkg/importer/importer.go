@@ -0,0 +1,83 @@
+// Package importer converts flashcards exported by other applications into
+// decks ready to be inserted with db.ImportDecks.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"go-flashcards-server/pkg/types"
+)
+
+// Importer reads the cards of one uploaded file.
+type Importer interface {
+	Import(r io.ReaderAt, size int64) (*Result, error)
+}
+
+// Result is what an importer read from a file. Skipped lists the items that
+// were dropped or changed on the way.
+type Result struct {
+	Decks        []types.ImportDeck
+	Skipped      []types.ImportIssue
+	MediaSkipped int
+}
+
+// Options configure the importer returned by New. Each format uses the
+// options that apply to it.
+type Options struct {
+	DeckName      string
+	Scheduling    bool
+	Trim          bool
+	TermSeparator string
+	CardSeparator string
+}
+
+// New returns the importer for format: apkg, quizlet or supermemo.
+func New(format string, opts Options) (Importer, error) {
+	switch format {
+	case "apkg":
+		return AnkiImporter{WithScheduling: opts.Scheduling}, nil
+	case "quizlet":
+		return QuizletImporter{DeckName: opts.DeckName, TermSeparator: opts.TermSeparator, CardSeparator: opts.CardSeparator, Trim: opts.Trim}, nil
+	case "supermemo":
+		return SuperMemoImporter{DeckName: opts.DeckName, Trim: opts.Trim}, nil
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+// separatorNames lets separators that are awkward to type in a query string
+// be given by name.
+var separatorNames = map[string]string{
+	"tab":       "\t",
+	"newline":   "\n",
+	"comma":     ",",
+	"semicolon": ";",
+	"dash":      " - ",
+}
+
+// ParseSeparator returns the separator named by value, or value itself. An
+// empty value returns fallback.
+func ParseSeparator(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	if sep, ok := separatorNames[strings.ToLower(value)]; ok {
+		return sep
+	}
+	return value
+}
+
+func readAll(r io.ReaderAt, size int64) ([]byte, error) {
+	return io.ReadAll(io.NewSectionReader(r, 0, size))
+}
+
+// cleanField trims a field when trim is set; otherwise only line endings
+// are normalized.
+func cleanField(field string, trim bool) string {
+	field = strings.ReplaceAll(field, "\r\n", "\n")
+	if trim {
+		return strings.TrimSpace(field)
+	}
+	return field
+}
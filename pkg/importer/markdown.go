@@ -0,0 +1,251 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-flashcards-server/pkg/types"
+	"go-flashcards-server/pkg/utils"
+)
+
+// Markdown deck files look like:
+//
+//	---
+//	id: 12
+//	tags: spanish, verbs
+//	---
+//
+//	# Spanish verbs
+//
+//	<!-- card: 34, tags: irregular -->
+//	ser :: to be
+//
+//	<!-- card: 35 -->
+//	Q: Conjugate "hablar" in the present tense.
+//	A: hablo, hablas, habla,
+//	  hablamos, habláis, hablan
+//
+// Front matter tags apply to every card. A comment before a card gives its
+// ID and any further tags; cards without one are new. Tags are separated by
+// commas and quoted as in CSV when they contain one. Question and answer
+// blocks continue on lines indented by two spaces and keep their text as
+// written, while "term :: definition" lines are trimmed.
+//
+// A file written by exporter.WriteMarkdown reads back to the same deck, with
+// two exceptions: line endings become "\n", and the deck name is a single
+// heading line, so line breaks and runs of spaces in it become one space.
+const (
+	markdownFrontMatter = "---"
+	markdownQuestion    = "Q:"
+	markdownAnswer      = "A:"
+	markdownTermSep     = " :: "
+	markdownIndent      = "  "
+)
+
+var markdownCardComment = regexp.MustCompile(`^<!--\s*(card(?::\s*(\d+))?)?\s*,?\s*(?:tags:\s*(.*?))?\s*-->$`)
+
+// MarkdownDeck is a deck read from a Markdown file. ID is the deck the file
+// was exported from, or 0.
+type MarkdownDeck struct {
+	ID    int
+	Name  string
+	Tags  []string
+	Cards []types.SyncCard
+}
+
+type markdownParser struct {
+	deck        *MarkdownDeck
+	issues      []types.ImportIssue
+	lines       []string
+	pos         int
+	pending     *types.SyncCard
+	pendingLine int
+}
+
+// ReadMarkdown parses a Markdown deck file. Malformed cards are reported and
+// skipped.
+func ReadMarkdown(data []byte) (*MarkdownDeck, []types.ImportIssue, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(trimBOM(data)))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	p := &markdownParser{deck: &MarkdownDeck{Tags: []string{}, Cards: []types.SyncCard{}}, issues: []types.ImportIssue{}, lines: lines}
+	if err := p.frontMatter(); err != nil {
+		return nil, nil, err
+	}
+	for p.pos < len(p.lines) {
+		p.line()
+	}
+	p.flushPending()
+	return p.deck, p.issues, nil
+}
+
+func (p *markdownParser) issue(line int, reason string) {
+	p.issues = append(p.issues, types.ImportIssue{Item: fmt.Sprintf("line %d", line), Reason: reason})
+}
+
+func (p *markdownParser) frontMatter() error {
+	start := p.pos
+	for start < len(p.lines) && isBlank(p.lines[start]) {
+		start++
+	}
+	if start == len(p.lines) || strings.TrimSpace(p.lines[start]) != markdownFrontMatter {
+		return nil
+	}
+	for i := start + 1; i < len(p.lines); i++ {
+		line := p.lines[i]
+		if strings.TrimSpace(line) == markdownFrontMatter {
+			p.pos = i + 1
+			return nil
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "id":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid deck id %q", i+1, value)
+			}
+			p.deck.ID = id
+		case "tags":
+			p.deck.Tags = splitMarkdownTags(strings.Trim(value, "[]"))
+		}
+	}
+	return fmt.Errorf("front matter is not closed")
+}
+
+func (p *markdownParser) line() {
+	lineNo := p.pos + 1
+	raw := p.lines[p.pos]
+	line := strings.TrimRight(raw, " \t")
+	p.pos++
+
+	switch {
+	case line == "":
+	case strings.HasPrefix(line, "<!--"):
+		match := markdownCardComment.FindStringSubmatch(line)
+		if match == nil || (match[1] == "" && match[3] == "") {
+			// Ordinary comments are notes for the reader.
+			return
+		}
+		p.flushPending()
+		card := &types.SyncCard{Tags: splitMarkdownTags(match[3])}
+		if match[2] != "" {
+			card.ID, _ = strconv.Atoi(match[2])
+		}
+		p.pending, p.pendingLine = card, lineNo
+	case strings.HasPrefix(line, "# "):
+		if p.deck.Name == "" {
+			p.deck.Name = strings.TrimSpace(line[2:])
+		}
+	case strings.HasPrefix(line, "#"):
+		// Lower level headings group cards for the reader only.
+	case strings.HasPrefix(line, markdownQuestion):
+		question := p.block(raw[len(markdownQuestion):])
+		for p.pos < len(p.lines) && isBlank(p.lines[p.pos]) {
+			p.pos++
+		}
+		if p.pos >= len(p.lines) || !strings.HasPrefix(p.lines[p.pos], markdownAnswer) {
+			p.issue(lineNo, "question without an answer")
+			p.pending = nil
+			return
+		}
+		p.pos++
+		answer := p.block(p.lines[p.pos-1][len(markdownAnswer):])
+		p.addCard(lineNo, question, answer)
+	case strings.Contains(line, markdownTermSep):
+		term, definition, _ := strings.Cut(line, markdownTermSep)
+		p.addCard(lineNo, strings.TrimSpace(term), strings.TrimSpace(definition))
+	default:
+		p.issue(lineNo, "unrecognized line")
+	}
+}
+
+// block reads the rest of a Q: or A: line, after the space that follows the
+// marker, and the indented lines after it. Unindented blank lines are kept
+// only when more indented text follows; indented ones always are.
+func (p *markdownParser) block(first string) string {
+	parts := []string{strings.TrimPrefix(first, " ")}
+	end := p.pos
+	for i := p.pos; i < len(p.lines); i++ {
+		line := p.lines[i]
+		indented := strings.HasPrefix(line, markdownIndent)
+		if !indented && !isBlank(line) {
+			break
+		}
+		if indented {
+			end = i + 1
+		}
+	}
+	for _, line := range p.lines[p.pos:end] {
+		if strings.HasPrefix(line, markdownIndent) {
+			parts = append(parts, line[len(markdownIndent):])
+		} else {
+			parts = append(parts, "")
+		}
+	}
+	p.pos = end
+	return strings.Join(parts, "\n")
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func (p *markdownParser) addCard(line int, question, answer string) {
+	card := types.SyncCard{}
+	if p.pending != nil {
+		card = *p.pending
+		p.pending = nil
+	}
+	card.Question, card.Answer = question, answer
+	if isBlank(card.Question) || isBlank(card.Answer) {
+		p.issue(line, "empty question or answer")
+		return
+	}
+	card.Tags = mergeMarkdownTags(p.deck.Tags, card.Tags)
+	p.deck.Cards = append(p.deck.Cards, card)
+}
+
+func (p *markdownParser) flushPending() {
+	if p.pending != nil {
+		p.issue(p.pendingLine, "card comment is not followed by a card")
+		p.pending = nil
+	}
+}
+
+// splitMarkdownTags reads a comma separated tag list, where a tag containing
+// a comma is quoted.
+func splitMarkdownTags(value string) []string {
+	reader := csv.NewReader(strings.NewReader(value))
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+	tags, err := reader.Read()
+	if err != nil {
+		tags = strings.Split(value, ",")
+	}
+	return utils.NormalizeTags(tags)
+}
+
+// mergeMarkdownTags combines deck and card tags into the sorted set stored
+// for a card.
+func mergeMarkdownTags(deckTags, cardTags []string) []string {
+	tags := utils.NormalizeTags(append(append([]string{}, deckTags...), cardTags...))
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"go-flashcards-server/pkg/exporter"
+	"go-flashcards-server/pkg/types"
+)
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		cards []types.ExportCard
+	}{
+		{name: "empty deck"},
+		{
+			name: "term lines",
+			cards: []types.ExportCard{
+				{ID: 1, Question: "ser", Answer: "to be"},
+				{ID: 2, Question: "ratio", Answer: "a :: b"},
+				{ID: 3, Question: "a:b", Answer: "::"},
+			},
+		},
+		{
+			name: "multi-line blocks",
+			cards: []types.ExportCard{
+				{ID: 1, Question: "Conjugate \"hablar\".", Answer: "hablo, hablas, habla,\nhablamos, habláis, hablan"},
+				{ID: 2, Question: "First\n\nthird", Answer: "  indented\n\tcode\n"},
+				{ID: 3, Question: "\nstarts on the second line", Answer: "two\n\n"},
+			},
+		},
+		{
+			name: "lines that look like markup",
+			cards: []types.ExportCard{
+				{ID: 1, Question: "# not a heading", Answer: "# still not"},
+				{ID: 2, Question: "Q: nested", Answer: "A: nested"},
+				{ID: 3, Question: "---", Answer: "---\nQ: x\nA: y\n# z\n<!-- card: 9 -->"},
+				{ID: 4, Question: "<!-- comment -->", Answer: "a :: b"},
+				{ID: 5, Question: "term :: like", Answer: "but a question"},
+			},
+		},
+		{
+			name: "whitespace",
+			cards: []types.ExportCard{
+				{ID: 1, Question: "trailing  ", Answer: "spaces\t"},
+				{ID: 2, Question: " leading", Answer: "line  \nbreaks  "},
+			},
+		},
+		{
+			name: "tags",
+			cards: []types.ExportCard{
+				{ID: 1, Question: "uno", Answer: "one", Tags: []string{"numbers", "spanish"}},
+				{ID: 2, Question: "dos", Answer: "two", Tags: []string{"a,b", "spanish", `say-"hi"`}},
+				{ID: 3, Question: "tres", Answer: "three", Tags: []string{"spanish"}},
+			},
+		},
+		{
+			name: "no common tags",
+			cards: []types.ExportCard{
+				{ID: 1, Question: "uno", Answer: "one", Tags: []string{"numbers"}},
+				{ID: 2, Question: "dos", Answer: "two"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck := &types.ExportDeck{ID: 7, Name: "Spanish: basics", Cards: tt.cards}
+			var file bytes.Buffer
+			if err := exporter.WriteMarkdown(&file, deck, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			read, issues, err := ReadMarkdown(file.Bytes())
+			if err != nil {
+				t.Fatalf("reading back: %v\n%s", err, file.String())
+			}
+			if len(issues) > 0 {
+				t.Errorf("issues = %+v\n%s", issues, file.String())
+			}
+			if read.ID != deck.ID || read.Name != deck.Name {
+				t.Errorf("deck = %d %q, want %d %q", read.ID, read.Name, deck.ID, deck.Name)
+			}
+			if len(read.Cards) != len(tt.cards) {
+				t.Fatalf("read %d cards, want %d\n%s", len(read.Cards), len(tt.cards), file.String())
+			}
+			for i, want := range tt.cards {
+				got := read.Cards[i]
+				if got.ID != want.ID || got.Question != want.Question || got.Answer != want.Answer {
+					t.Errorf("card %d = %d %q %q, want %d %q %q", i, got.ID, got.Question, got.Answer, want.ID, want.Question, want.Answer)
+				}
+				wantTags := slices.Sorted(slices.Values(want.Tags))
+				if !slices.Equal(got.Tags, wantTags) && (len(got.Tags) > 0 || len(wantTags) > 0) {
+					t.Errorf("card %d tags = %q, want %q", i, got.Tags, wantTags)
+				}
+			}
+		})
+	}
+}
+
+// Deck names are a single heading line.
+func TestMarkdownDeckNameIsOneLine(t *testing.T) {
+	var file bytes.Buffer
+	if err := exporter.WriteMarkdown(&file, &types.ExportDeck{Name: " Spanish\nverbs  "}, nil); err != nil {
+		t.Fatal(err)
+	}
+	read, _, err := ReadMarkdown(file.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Name != "Spanish verbs" {
+		t.Errorf("name = %q, want %q", read.Name, "Spanish verbs")
+	}
+}
@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go-flashcards-server/pkg/types"
+)
+
+const (
+	superMemoQuestion = "Q:"
+	superMemoAnswer   = "A:"
+)
+
+// QuizletImporter reads Quizlet's text export: a term and definition per
+// card, separated by TermSeparator (tab by default), with cards separated by
+// CardSeparator (newline by default).
+type QuizletImporter struct {
+	DeckName      string
+	TermSeparator string
+	CardSeparator string
+	Trim          bool
+}
+
+func (q QuizletImporter) Import(r io.ReaderAt, size int64) (*Result, error) {
+	termSep := ParseSeparator(q.TermSeparator, "\t")
+	cardSep := ParseSeparator(q.CardSeparator, "\n")
+	if termSep == cardSep {
+		return nil, errors.New("term and card separators must differ")
+	}
+	data, err := readAll(r, size)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(string(trimBOM(data)), "\r\n", "\n")
+
+	deck := types.ImportDeck{Name: q.DeckName}
+	result := &Result{Skipped: []types.ImportIssue{}}
+	for i, record := range strings.Split(text, cardSep) {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		item := fmt.Sprintf("card %d", i+1)
+		term, definition, ok := strings.Cut(record, termSep)
+		if !ok {
+			result.Skipped = append(result.Skipped, types.ImportIssue{Item: item, Reason: "missing term separator"})
+			continue
+		}
+		card := types.ImportCard{Question: cleanField(term, q.Trim), Answer: cleanField(definition, q.Trim)}
+		if strings.TrimSpace(card.Question) == "" || strings.TrimSpace(card.Answer) == "" {
+			result.Skipped = append(result.Skipped, types.ImportIssue{Item: item, Reason: "empty term or definition"})
+			continue
+		}
+		deck.Cards = append(deck.Cards, card)
+	}
+	result.Decks = []types.ImportDeck{deck}
+	return result, nil
+}
+
+// SuperMemoImporter reads SuperMemo Q&A text: "Q:" and "A:" lines, with
+// cards separated by blank lines. Repeated or unprefixed lines continue the
+// current field.
+type SuperMemoImporter struct {
+	DeckName string
+	Trim     bool
+}
+
+func (s SuperMemoImporter) Import(r io.ReaderAt, size int64) (*Result, error) {
+	data, err := readAll(r, size)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.ReplaceAll(string(trimBOM(data)), "\r\n", "\n"), "\n")
+
+	deck := types.ImportDeck{Name: s.DeckName}
+	result := &Result{Skipped: []types.ImportIssue{}}
+	var question, answer []string
+	current := &question
+	start := 0
+	flush := func() {
+		if question == nil && answer == nil {
+			return
+		}
+		item := fmt.Sprintf("line %d", start)
+		card := types.ImportCard{
+			Question: cleanField(strings.Join(question, "\n"), s.Trim),
+			Answer:   cleanField(strings.Join(answer, "\n"), s.Trim),
+		}
+		if strings.TrimSpace(card.Question) == "" || strings.TrimSpace(card.Answer) == "" {
+			result.Skipped = append(result.Skipped, types.ImportIssue{Item: item, Reason: "missing question or answer"})
+		} else {
+			deck.Cards = append(deck.Cards, card)
+		}
+		question, answer, current = nil, nil, &question
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+			continue
+		case strings.HasPrefix(trimmed, superMemoQuestion):
+			if answer != nil {
+				flush()
+			}
+			current, line = &question, strings.TrimPrefix(trimmed, superMemoQuestion)
+		case strings.HasPrefix(trimmed, superMemoAnswer):
+			current, line = &answer, strings.TrimPrefix(trimmed, superMemoAnswer)
+		}
+		if question == nil && answer == nil {
+			start = i + 1
+		}
+		if s.Trim {
+			line = strings.TrimSpace(line)
+		} else {
+			line = strings.TrimPrefix(line, " ")
+		}
+		*current = append(*current, line)
+	}
+	flush()
+
+	result.Decks = []types.ImportDeck{deck}
+	return result, nil
+}
@@ -0,0 +1,246 @@
+// Package jobs runs imports and exports in the background. Jobs are stored in
+// the database, so queued work survives a restart and any worker may pick it
+// up. A running job renews a short lease; a job whose worker stopped renewing
+// it, because its server stopped or crashed, is queued again.
+package jobs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/dedupe"
+	"go-flashcards-server/pkg/exporter"
+	"go-flashcards-server/pkg/importer"
+	"go-flashcards-server/pkg/types"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	// renewInterval is how often a running job records its progress and
+	// renews its lease.
+	renewInterval = 2 * time.Second
+	// jobLease is how long a job's lease lasts without being renewed before
+	// the job is taken to be abandoned by its worker, and is run again.
+	jobLease        = time.Minute
+	requeueInterval = 30 * time.Second
+)
+
+// wake lets Enqueue start an idle worker without waiting for the next poll.
+var wake = make(chan struct{}, 1)
+
+// errJob is a failure caused by the job's input, reported to the user as is.
+type errJob struct{ message string }
+
+func (e errJob) Error() string { return e.message }
+
+// Start starts the workers, and periodically requeues jobs whose worker
+// stopped, on this or another server, while running them. Jobs this server
+// was running when it stopped are requeued once their lease runs out.
+func Start(workers int) {
+	go func() {
+		for {
+			if requeued, err := db.RequeueStaleJobs(jobLease); err == nil && requeued > 0 {
+				log.Printf("Requeued %d interrupted jobs", requeued)
+			}
+			time.Sleep(requeueInterval)
+		}
+	}()
+	for i := 0; i < workers; i++ {
+		go work()
+	}
+}
+
+// Enqueue stores a job and returns its ID.
+func Enqueue(userID int, kind string, params types.JobParams, input []byte) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	if err := db.CreateJob(id, userID, kind, params, input); err != nil {
+		return "", err
+	}
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+	return id, nil
+}
+
+func work() {
+	for {
+		task, err := db.ClaimJob()
+		if err != nil || task == nil {
+			select {
+			case <-wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		run(task)
+	}
+}
+
+func run(task *types.JobTask) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("Job %s panicked: %v", task.ID, p)
+			db.FailJob(task.ID, "Internal error")
+		}
+	}()
+
+	var progress atomic.Int32
+	done := make(chan struct{})
+	defer close(done)
+	go renew(task.ID, &progress, done)
+
+	var err error
+	switch task.Kind {
+	case types.JobImport:
+		err = runImport(task, &progress)
+	case types.JobExport:
+		err = runExport(task, &progress)
+	default:
+		err = errJob{fmt.Sprintf("unknown job kind %q", task.Kind)}
+	}
+	if err == nil {
+		return
+	}
+
+	var jobErr errJob
+	if errors.As(err, &jobErr) {
+		db.FailJob(task.ID, jobErr.message)
+	} else {
+		log.Printf("Job %s failed: %v", task.ID, err)
+		db.FailJob(task.ID, "Internal error")
+	}
+}
+
+// renew records the job's progress and renews its lease until done is
+// closed. Progress is written here rather than by the job itself, since on
+// SQLite the job's transaction holds the write lock until it commits.
+func renew(id string, progress *atomic.Int32, done <-chan struct{}) {
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			db.RenewJob(id, int(progress.Load()))
+		}
+	}
+}
+
+// span reports items done as progress between from and to percent.
+func span(progress *atomic.Int32, from, to int) types.Progress {
+	return func(done, total int) {
+		progress.Store(int32(from + (to-from)*done/total))
+	}
+}
+
+func runImport(task *types.JobTask, progress *atomic.Int32) error {
+	params := task.Params
+	if params.Format == "md" {
+		return runMarkdownImport(task, progress)
+	}
+
+	imp, err := importer.New(params.Format, importer.Options{
+		DeckName:      params.DeckName,
+		Scheduling:    params.Scheduling,
+		Trim:          params.Trim,
+		TermSeparator: params.TermSeparator,
+		CardSeparator: params.CardSeparator,
+	})
+	if err != nil {
+		return errJob{err.Error()}
+	}
+	result, err := imp.Import(bytes.NewReader(task.Input), int64(len(task.Input)))
+	if err != nil {
+		return errJob{"Could not read file: " + err.Error()}
+	}
+	progress.Store(10)
+
+	if params.DeckID != 0 {
+		for i := range result.Decks {
+			result.Decks[i].ID = params.DeckID
+		}
+	}
+	report, err := db.ImportDecks(task.UserID, result.Decks, params.Duplicates, span(progress, 10, 100))
+	if errors.Is(err, dedupe.ErrTooManyCards) {
+		return errJob{fmt.Sprintf("Near-duplicate matching is limited to %d cards", dedupe.MaxFuzzyCards)}
+	}
+	if err != nil {
+		return err
+	}
+	report.Skipped = append(report.Skipped, result.Skipped...)
+	report.MediaSkipped = result.MediaSkipped
+	return db.FinishJob(task.ID, report, "", "", nil)
+}
+
+// runMarkdownImport syncs a Markdown deck file. Access to the deck named in
+// the file is checked here since it is only known once the file is read.
+func runMarkdownImport(task *types.JobTask, progress *atomic.Int32) error {
+	deck, issues, err := importer.ReadMarkdown(task.Input)
+	if err != nil {
+		return errJob{"Could not read file: " + err.Error()}
+	}
+	progress.Store(10)
+
+	deckID := deck.ID
+	if task.Params.DeckID != 0 {
+		deckID = task.Params.DeckID
+	}
+	if deckID != 0 {
+		role, err := db.GetDeckRole(deckID, task.UserID)
+		if err != nil {
+			return err
+		}
+		if !types.RoleAllows(role, types.RoleEditor) {
+			return errJob{"Deck not found"}
+		}
+	} else if deck.Name == "" {
+		deck.Name = task.Params.DeckName
+	}
+
+	report, err := db.SyncDeck(task.UserID, deckID, deck.Name, deck.Cards, task.Params.Prune, span(progress, 10, 100))
+	if err != nil {
+		return err
+	}
+	report.Skipped = append(report.Skipped, issues...)
+	return db.FinishJob(task.ID, report, "", "", nil)
+}
+
+func runExport(task *types.JobTask, progress *atomic.Int32) error {
+	params := task.Params
+	contentType, ok := exporter.ContentType(params.Format)
+	if !ok {
+		return errJob{fmt.Sprintf("unknown export format %q", params.Format)}
+	}
+	role, err := db.GetDeckRole(params.DeckID, task.UserID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return errJob{"Deck not found"}
+	}
+
+	deck, err := db.GetExportDeck(params.DeckID, task.UserID, params.Scheduling)
+	if err != nil {
+		return err
+	}
+	progress.Store(10)
+
+	var buf bytes.Buffer
+	if err := exporter.Write(&buf, params.Format, deck, params.Scheduling, span(progress, 10, 100)); err != nil {
+		return err
+	}
+	return db.FinishJob(task.ID, nil, exporter.Filename(deck.Name, params.Format), contentType, buf.Bytes())
+}
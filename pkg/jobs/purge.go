@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"go-flashcards-server/pkg/db"
+)
+
+const purgeInterval = time.Hour
+
+// StartPurge periodically removes trash older than retentionDays.
+func StartPurge(retentionDays int) {
+	go func() {
+		for {
+			decks, cards, err := db.PurgeTrash(retentionDays)
+			if err == nil && decks+cards > 0 {
+				log.Printf("Purged %d decks and %d cards from the trash", decks, cards)
+			}
+			time.Sleep(purgeInterval)
+		}
+	}()
+}
@@ -0,0 +1,564 @@
+// Package live runs real-time multiplayer quiz games. Games only live in
+// memory: a host starts one from a deck, players join with its PIN, and
+// questions, answers and leaderboards are exchanged over WebSocket.
+package live
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	mathrand "math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	maxChoices      = 4
+	maxGameDuration = 3 * time.Hour
+	sendBuffer      = 16
+	writeWait       = 10 * time.Second
+	pongWait        = 60 * time.Second
+	pingPeriod      = pongWait * 9 / 10
+	maxMessageSize  = 4096
+	// maxPlayers bounds a game, since every join and answer is broadcast
+	// to all players.
+	maxPlayers = 100
+)
+
+const (
+	stateLobby    = "lobby"
+	stateQuestion = "question"
+	stateResults  = "results"
+	stateFinished = "finished"
+)
+
+var (
+	ErrNotEnoughCards = errors.New("deck needs at least two cards with different answers")
+	ErrNameTaken      = errors.New("name already taken")
+	ErrGameFinished   = errors.New("game has finished")
+	ErrGameStarted    = errors.New("game has already started")
+	ErrGameFull       = errors.New("game is full")
+	ErrHostConnected  = errors.New("host already connected")
+)
+
+var (
+	gamesMu sync.Mutex
+	games   = map[string]*Game{}
+)
+
+type Card struct {
+	Question string
+	Answer   string
+}
+
+type question struct {
+	Prompt  string
+	Choices []string
+	Correct int
+}
+
+type Message struct {
+	Type        string     `json:"type"`
+	PIN         string     `json:"pin,omitempty"`
+	Players     []string   `json:"players,omitempty"`
+	Index       int        `json:"index"`
+	Total       int        `json:"total,omitempty"`
+	Prompt      string     `json:"prompt,omitempty"`
+	Choices     []string   `json:"choices,omitempty"`
+	Seconds     int        `json:"seconds,omitempty"`
+	Choice      *int       `json:"choice,omitempty"`
+	Correct     *int       `json:"correct,omitempty"`
+	Points      int        `json:"points,omitempty"`
+	Answered    int        `json:"answered,omitempty"`
+	Leaderboard []Standing `json:"leaderboard,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+type Standing struct {
+	Rank  int    `json:"rank"`
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+// client is a connected host or player. send and closed are guarded by the
+// owning game's mutex.
+type client struct {
+	conn   *websocket.Conn
+	send   chan []byte
+	closed bool
+}
+
+type player struct {
+	*client
+	name     string
+	score    int
+	answered bool
+}
+
+type Game struct {
+	PIN      string
+	HostID   int
+	DeckName string
+
+	questions []question
+	duration  time.Duration
+
+	mu         sync.Mutex
+	host       *client
+	players    map[string]*player
+	state      string
+	current    int
+	roundStart time.Time
+	roundTimer *time.Timer
+	expiry     *time.Timer
+}
+
+// NewGame creates a game in the lobby state and registers it under a fresh PIN.
+func NewGame(hostID int, deckName string, cards []Card, duration time.Duration) (*Game, error) {
+	questions, err := buildQuestions(cards)
+	if err != nil {
+		return nil, err
+	}
+
+	game := &Game{
+		HostID:    hostID,
+		DeckName:  deckName,
+		questions: questions,
+		duration:  duration,
+		players:   map[string]*player{},
+		state:     stateLobby,
+		current:   -1,
+	}
+
+	gamesMu.Lock()
+	defer gamesMu.Unlock()
+	for {
+		pin, err := generatePIN()
+		if err != nil {
+			return nil, err
+		}
+		if _, taken := games[pin]; !taken {
+			game.PIN = pin
+			break
+		}
+	}
+	games[game.PIN] = game
+	game.expiry = time.AfterFunc(maxGameDuration, game.finish)
+	return game, nil
+}
+
+// Find returns the running game with the given PIN, or nil.
+func Find(pin string) *Game {
+	gamesMu.Lock()
+	defer gamesMu.Unlock()
+	return games[pin]
+}
+
+func generatePIN() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// buildQuestions turns each card into a multiple-choice question whose wrong
+// choices are answers of other cards in the deck.
+func buildQuestions(cards []Card) ([]question, error) {
+	answers := []string{}
+	seen := map[string]bool{}
+	for _, card := range cards {
+		if !seen[card.Answer] {
+			seen[card.Answer] = true
+			answers = append(answers, card.Answer)
+		}
+	}
+	if len(answers) < 2 {
+		return nil, ErrNotEnoughCards
+	}
+
+	questions := make([]question, 0, len(cards))
+	for _, card := range cards {
+		choices := []string{card.Answer}
+		for _, i := range mathrand.Perm(len(answers)) {
+			if len(choices) == maxChoices {
+				break
+			}
+			if answers[i] != card.Answer {
+				choices = append(choices, answers[i])
+			}
+		}
+		mathrand.Shuffle(len(choices), func(i, j int) { choices[i], choices[j] = choices[j], choices[i] })
+		correct := 0
+		for i, choice := range choices {
+			if choice == card.Answer {
+				correct = i
+			}
+		}
+		questions = append(questions, question{Prompt: card.Question, Choices: choices, Correct: correct})
+	}
+	mathrand.Shuffle(len(questions), func(i, j int) { questions[i], questions[j] = questions[j], questions[i] })
+	return questions, nil
+}
+
+func newClient(conn *websocket.Conn) *client {
+	c := &client{conn: conn, send: make(chan []byte, sendBuffer)}
+	go c.writePump()
+	return c
+}
+
+// close stops the writer, which then closes the connection.
+func (c *client) close() {
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readLoop decodes messages from the client until the connection closes.
+func (c *client) readLoop(handle func(Message)) {
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+	for {
+		var msg Message
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("Live game connection closed: %v", err)
+			}
+			return
+		}
+		handle(msg)
+	}
+}
+
+// deliver queues a message without blocking; a client that cannot keep up is
+// disconnected.
+func (c *client) deliver(data []byte) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		c.conn.Close()
+	}
+}
+
+// ServeHost attaches the host connection and processes its commands until it
+// disconnects, which ends the game.
+func (g *Game) ServeHost(conn *websocket.Conn) error {
+	c := newClient(conn)
+	g.mu.Lock()
+	if g.state == stateFinished {
+		g.sendLocked(c, Message{Type: "error", Error: ErrGameFinished.Error()})
+		c.close()
+		g.mu.Unlock()
+		return ErrGameFinished
+	}
+	if g.host != nil {
+		g.sendLocked(c, Message{Type: "error", Error: ErrHostConnected.Error()})
+		c.close()
+		g.mu.Unlock()
+		return ErrHostConnected
+	}
+	g.host = c
+	g.sendLocked(c, Message{Type: stateLobby, PIN: g.PIN, Total: len(g.questions), Players: g.playerNamesLocked()})
+	g.mu.Unlock()
+
+	c.readLoop(func(msg Message) {
+		switch msg.Type {
+		case "start", "next":
+			g.advance()
+		case "end":
+			g.finish()
+		default:
+			g.mu.Lock()
+			g.sendLocked(c, Message{Type: "error", Error: "unknown command"})
+			g.mu.Unlock()
+		}
+	})
+	g.finish()
+	return nil
+}
+
+// CanJoin reports why a player could not join right now, so callers can
+// refuse before upgrading the connection. ServePlayer checks again.
+func (g *Game) CanJoin(name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.joinableLocked(name)
+}
+
+// joinableLocked allows joins only in the lobby and up to maxPlayers.
+func (g *Game) joinableLocked(name string) error {
+	switch {
+	case g.state == stateFinished:
+		return ErrGameFinished
+	case g.state != stateLobby:
+		return ErrGameStarted
+	case len(g.players) >= maxPlayers:
+		return ErrGameFull
+	}
+	if _, taken := g.players[name]; taken {
+		return ErrNameTaken
+	}
+	return nil
+}
+
+// ServePlayer adds a player and processes their answers until they disconnect.
+func (g *Game) ServePlayer(conn *websocket.Conn, name string) error {
+	c := newClient(conn)
+	g.mu.Lock()
+	if err := g.joinableLocked(name); err != nil {
+		g.sendLocked(c, Message{Type: "error", Error: err.Error()})
+		c.close()
+		g.mu.Unlock()
+		return err
+	}
+	p := &player{client: c, name: name}
+	g.players[name] = p
+	g.sendLocked(c, Message{Type: stateLobby, PIN: g.PIN, Total: len(g.questions)})
+	g.broadcastPlayersLocked()
+	g.mu.Unlock()
+
+	c.readLoop(func(msg Message) {
+		if msg.Type == "answer" && msg.Choice != nil {
+			g.answer(p, msg.Index, *msg.Choice)
+		}
+	})
+
+	g.mu.Lock()
+	if g.players[name] == p {
+		delete(g.players, name)
+		c.close()
+		if g.state != stateFinished {
+			g.broadcastPlayersLocked()
+		}
+		if g.state == stateQuestion && len(g.players) > 0 && g.answeredLocked() == len(g.players) {
+			g.closeRoundLocked()
+		}
+	}
+	g.mu.Unlock()
+	return nil
+}
+
+// advance starts the next question, or ends the game after the last one.
+func (g *Game) advance() {
+	g.mu.Lock()
+	if g.state == stateQuestion || g.state == stateFinished {
+		g.mu.Unlock()
+		return
+	}
+	if g.current+1 >= len(g.questions) {
+		g.mu.Unlock()
+		g.finish()
+		return
+	}
+	g.current++
+	g.state = stateQuestion
+	g.roundStart = time.Now()
+	for _, p := range g.players {
+		p.answered = false
+	}
+	q := g.questions[g.current]
+	msg := Message{
+		Type:    stateQuestion,
+		Index:   g.current,
+		Total:   len(g.questions),
+		Prompt:  q.Prompt,
+		Choices: q.Choices,
+		Seconds: int(g.duration.Seconds()),
+	}
+	g.broadcastLocked(msg)
+	index := g.current
+	g.roundTimer = time.AfterFunc(g.duration, func() { g.closeRound(index) })
+	g.mu.Unlock()
+}
+
+// answer scores a player's choice: correct answers earn 500 to 1000 points
+// depending on how quickly they came in.
+func (g *Game) answer(p *player, index, choice int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.state != stateQuestion || index != g.current || p.answered {
+		return
+	}
+	p.answered = true
+
+	q := g.questions[g.current]
+	points := 0
+	if choice == q.Correct {
+		elapsed := time.Since(g.roundStart)
+		remaining := max(0, 1-float64(elapsed)/float64(g.duration))
+		points = 500 + int(500*remaining)
+	}
+	p.score += points
+	correct := q.Correct
+	g.sendLocked(p.client, Message{Type: "answered", Index: index, Choice: &choice, Correct: &correct, Points: points})
+
+	answered := g.answeredLocked()
+	if g.host != nil {
+		g.sendLocked(g.host, Message{Type: "answered", Index: index, Answered: answered, Total: len(g.players)})
+	}
+	if answered == len(g.players) {
+		g.closeRoundLocked()
+	}
+}
+
+func (g *Game) answeredLocked() int {
+	answered := 0
+	for _, p := range g.players {
+		if p.answered {
+			answered++
+		}
+	}
+	return answered
+}
+
+func (g *Game) closeRound(index int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.state == stateQuestion && g.current == index {
+		g.closeRoundLocked()
+	}
+}
+
+func (g *Game) closeRoundLocked() {
+	if g.roundTimer != nil {
+		g.roundTimer.Stop()
+	}
+	g.state = stateResults
+	correct := g.questions[g.current].Correct
+	g.broadcastLocked(Message{
+		Type:        "round_result",
+		Index:       g.current,
+		Total:       len(g.questions),
+		Correct:     &correct,
+		Leaderboard: g.leaderboardLocked(),
+	})
+}
+
+// finish broadcasts the final leaderboard, closes every connection and
+// unregisters the game. It is safe to call more than once.
+func (g *Game) finish() {
+	g.mu.Lock()
+	if g.state == stateFinished {
+		g.mu.Unlock()
+		return
+	}
+	g.state = stateFinished
+	if g.roundTimer != nil {
+		g.roundTimer.Stop()
+	}
+	if g.expiry != nil {
+		g.expiry.Stop()
+	}
+	g.broadcastLocked(Message{Type: "game_over", Total: len(g.questions), Leaderboard: g.leaderboardLocked()})
+	for name, p := range g.players {
+		p.close()
+		delete(g.players, name)
+	}
+	if g.host != nil {
+		g.host.close()
+	}
+	g.mu.Unlock()
+
+	gamesMu.Lock()
+	if games[g.PIN] == g {
+		delete(games, g.PIN)
+	}
+	gamesMu.Unlock()
+}
+
+func (g *Game) leaderboardLocked() []Standing {
+	standings := make([]Standing, 0, len(g.players))
+	for _, p := range g.players {
+		standings = append(standings, Standing{Name: p.name, Score: p.score})
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].Score != standings[j].Score {
+			return standings[i].Score > standings[j].Score
+		}
+		return standings[i].Name < standings[j].Name
+	})
+	for i := range standings {
+		if i > 0 && standings[i].Score == standings[i-1].Score {
+			standings[i].Rank = standings[i-1].Rank
+		} else {
+			standings[i].Rank = i + 1
+		}
+	}
+	return standings
+}
+
+func (g *Game) playerNamesLocked() []string {
+	names := make([]string, 0, len(g.players))
+	for name := range g.players {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g *Game) broadcastPlayersLocked() {
+	g.broadcastLocked(Message{Type: "players", Players: g.playerNamesLocked()})
+}
+
+func (g *Game) broadcastLocked(msg Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error encoding live game message: %v", err)
+		return
+	}
+	if g.host != nil {
+		g.host.deliver(data)
+	}
+	for _, p := range g.players {
+		p.deliver(data)
+	}
+}
+
+func (g *Game) sendLocked(c *client, msg Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error encoding live game message: %v", err)
+		return
+	}
+	c.deliver(data)
+}
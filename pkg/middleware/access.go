@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"go-flashcards-server/pkg/db"
+	"go-flashcards-server/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// AccessMiddleware answers 404 for any route whose {deck_id} or {card_id}
+// names a deck or card the caller cannot see, so a route cannot expose another
+// user's data by missing its own check. Handlers still check the role an
+// action needs; this only settles whether the resource exists for the caller.
+// It must run after AuthMiddleware.
+func AccessMiddleware(store db.Store) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return accessHandler(store, next)
+	}
+}
+
+func accessHandler(store db.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			utils.HandleErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		vars := mux.Vars(r)
+
+		// Malformed IDs are left to the handlers, which answer 400.
+		if cardID, err := strconv.Atoi(vars["card_id"]); err == nil {
+			deckID, err := store.GetCardDeckID(cardID)
+			if err != nil {
+				utils.HandleErrorResponse(w, "Error checking card access", http.StatusInternalServerError)
+				return
+			}
+			if !allowed(store, w, deckID, userID, "Card not found") {
+				return
+			}
+		}
+		if deckID, err := strconv.Atoi(vars["deck_id"]); err == nil {
+			if !allowed(store, w, deckID, userID, "Deck not found") {
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowed reports whether the user holds any role on the deck, writing the
+// error response when they do not.
+func allowed(store db.Store, w http.ResponseWriter, deckID, userID int, notFound string) bool {
+	if deckID == 0 {
+		utils.HandleErrorResponse(w, notFound, http.StatusNotFound)
+		return false
+	}
+	role, err := store.GetDeckRole(deckID, userID)
+	if err != nil {
+		utils.HandleErrorResponse(w, "Error checking deck access", http.StatusInternalServerError)
+		return false
+	}
+	if role == "" {
+		utils.HandleErrorResponse(w, notFound, http.StatusNotFound)
+		return false
+	}
+	return true
+}
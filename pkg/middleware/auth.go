@@ -46,6 +46,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		userID, err := strconv.Atoi(sub)
 		if err != nil {
 			utils.HandleErrorResponse(w, "Invalid User ID", http.StatusUnauthorized)
+			return
 		}
 
 		ctx := context.WithValue(r.Context(), "userID", userID)
@@ -1,21 +1,35 @@
 package middleware
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"mime"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// maxLoggedBody caps how much of a request or response body is logged.
+const maxLoggedBody = 4 << 10
+
 type responseRecorder struct {
 	http.ResponseWriter
 	statusCode int
 	body       *bytes.Buffer
+	size       int
 }
 
 func (rec *responseRecorder) Write(b []byte) (int, error) {
-	rec.body.Write(b)                  // Capture the response body
+	// Capture the start of a textual response body
+	if room := maxLoggedBody - rec.body.Len(); room > 0 && loggable(rec.Header().Get("Content-Type")) {
+		rec.body.Write(b[:min(len(b), room)])
+	}
+	rec.size += len(b)
 	return rec.ResponseWriter.Write(b) // Write to the original response
 }
 
@@ -24,17 +38,27 @@ func (rec *responseRecorder) WriteHeader(statusCode int) {
 	rec.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Hijack lets WebSocket upgrades take over the connection through the recorder.
+func (rec *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	rec.statusCode = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		log.Printf("Incoming Request: Method=%s, Path=%s, RemoteAddr=%s", r.Method, r.URL.Path, r.RemoteAddr)
 
-		var requestBody []byte
-		if r.Body != nil {
-			requestBody, _ = io.ReadAll(r.Body)
+		// Uploads are passed through unread rather than buffered for the log.
+		if r.Body != nil && loggable(r.Header.Get("Content-Type")) {
+			requestBody, _ := io.ReadAll(r.Body)
 			r.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+			log.Printf("Request Body: %s", truncate(requestBody, len(requestBody)))
 		}
-		log.Printf("Request Body: %s", string(requestBody))
 
 		recorder := &responseRecorder{
 			ResponseWriter: w,
@@ -46,6 +70,29 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 
 		duration := time.Since(start)
 		log.Printf("Response: Status=%d, Duration=%s", recorder.statusCode, duration)
-		log.Printf("Response Body: %s", recorder.body.String())
+		if recorder.body.Len() > 0 {
+			log.Printf("Response Body: %s", truncate(recorder.body.Bytes(), recorder.size))
+		}
 	})
 }
+
+// loggable reports whether a body of the content type is text worth logging.
+func loggable(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" ||
+		mediaType == "application/x-www-form-urlencoded"
+}
+
+// truncate returns the logged part of a body of size bytes.
+func truncate(body []byte, size int) string {
+	if size <= maxLoggedBody {
+		return string(body)
+	}
+	return fmt.Sprintf("%s... (%d bytes)", body[:min(len(body), maxLoggedBody)], size)
+}
@@ -0,0 +1,62 @@
+// Package srs implements the SM-2 spaced repetition schedule used for reviews.
+package srs
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	MinGrade    = 0
+	MaxGrade    = 5
+	PassGrade   = 3
+	DefaultEase = 2.5
+	minEase     = 1.3
+)
+
+type State struct {
+	IntervalDays int
+	Ease         float64
+	Reps         int
+	Lapses       int
+}
+
+// NewState returns the scheduling state of a card that has never been reviewed.
+func NewState() State {
+	return State{Ease: DefaultEase}
+}
+
+// Next applies a review graded 0-5 to s and returns the updated state.
+func Next(s State, grade int) State {
+	if s.Ease == 0 {
+		s.Ease = DefaultEase
+	}
+
+	if grade < PassGrade {
+		s.Reps = 0
+		s.Lapses++
+		s.IntervalDays = 1
+	} else {
+		switch s.Reps {
+		case 0:
+			s.IntervalDays = 1
+		case 1:
+			s.IntervalDays = 6
+		default:
+			s.IntervalDays = int(math.Round(float64(s.IntervalDays) * s.Ease))
+		}
+		s.Reps++
+	}
+
+	q := float64(grade)
+	s.Ease += 0.1 - (5-q)*(0.08+(5-q)*0.02)
+	if s.Ease < minEase {
+		s.Ease = minEase
+	}
+	return s
+}
+
+// DueAt returns when a card reviewed at reviewedAt with state s is next due.
+func DueAt(s State, reviewedAt time.Time) time.Time {
+	return reviewedAt.AddDate(0, 0, s.IntervalDays)
+}
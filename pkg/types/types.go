@@ -1,14 +1,564 @@
 package types
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type GCResponse[T any] struct {
-	IsOK    bool   `json:"IsOK"`
-	Message string `json:"Message"`
-	Payload *T     `json:"Payload,omitempty"`
+	IsOK       bool   `json:"IsOK"`
+	Message    string `json:"Message"`
+	Payload    *T     `json:"Payload,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// User is an account as stored. The password hash and salt never leave the
+// server.
+type User struct {
+	ID           int    `json:"id"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"-"`
+	Salt         string `json:"-"`
 }
 
 type Deck struct {
-	ID        int    `json:"id"`
+	ID        int     `json:"id"`
+	UserID    int     `json:"user_id"`
+	Name      string  `json:"name"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
+	Role      string  `json:"role"`
+	Position  int64   `json:"position"`
+	NextDueAt *string `json:"next_due_at,omitempty"`
+
+	CardCount     int     `json:"card_count"`
+	NewCount      int     `json:"new_count"`
+	LearningCount int     `json:"learning_count"`
+	ReviewCount   int     `json:"review_count"`
+	LastStudiedAt *string `json:"last_studied_at,omitempty"`
+}
+
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+type DeckShare struct {
+	DeckID    int    `json:"deck_id"`
 	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+type CardState struct {
+	CardID         int     `json:"card_id"`
+	DueAt          string  `json:"due_at"`
+	IntervalDays   int     `json:"interval_days"`
+	Ease           float64 `json:"ease"`
+	Reps           int     `json:"reps"`
+	Lapses         int     `json:"lapses"`
+	LastReviewedAt *string `json:"last_reviewed_at,omitempty"`
+}
+
+var roleRank = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// RoleAllows reports whether role grants at least the access of required.
+func RoleAllows(role, required string) bool {
+	return role != "" && roleRank[role] >= roleRank[required]
+}
+
+type Card struct {
+	ID        int     `json:"id"`
+	DeckID    int     `json:"deck_id"`
+	Question  string  `json:"question"`
+	Answer    string  `json:"answer"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at,omitempty"`
+	Position  int64   `json:"position"`
+	DueAt     *string `json:"due_at,omitempty"`
+}
+
+type CatalogEntry struct {
+	ID          int      `json:"id"`
+	DeckID      int      `json:"deck_id"`
+	UserID      int      `json:"user_id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Language    string   `json:"language"`
+	Tags        []string `json:"tags"`
+	CardCount   int      `json:"card_count"`
+	CloneCount  int      `json:"clone_count"`
+	PublishedAt string   `json:"published_at"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+const (
+	SortPosition = "position"
+	SortCreated  = "created"
+	SortUpdated  = "updated"
+	SortDue      = "due"
+	SortAlpha    = "alpha"
+)
+
+const (
+	CardStatusNew    = "new"
+	CardStatusReview = "review"
+	CardStatusDue    = "due"
+)
+
+// ListFilter selects, sorts and pages a listing of decks or cards. Cursor is
+// the next_cursor of the previous page. Role applies to decks; Tag and Status
+// apply to cards.
+type ListFilter struct {
+	Query  string
+	Role   string
+	Tag    string
+	Status string
+	Sort   string
+	Desc   bool
+	Limit  int
+	Cursor string
+}
+
+type CatalogFilter struct {
+	Query    string
+	Language string
+	Tag      string
+	Limit    int
+	Offset   int
+}
+
+type DeckFork struct {
+	DeckID       int    `json:"deck_id"`
+	SourceDeckID int    `json:"source_deck_id"`
+	SyncedAt     string `json:"synced_at"`
+}
+
+// CardChange describes one card that differs between a forked deck and its
+// upstream deck.
+type CardChange struct {
+	CardID           int    `json:"card_id,omitempty"`
+	SourceCardID     int    `json:"source_card_id"`
+	LocalQuestion    string `json:"local_question,omitempty"`
+	LocalAnswer      string `json:"local_answer,omitempty"`
+	UpstreamQuestion string `json:"upstream_question,omitempty"`
+	UpstreamAnswer   string `json:"upstream_answer,omitempty"`
+	Conflict         bool   `json:"conflict"`
+}
+
+type UpstreamDiff struct {
+	SourceDeckID int          `json:"source_deck_id"`
+	SyncedAt     string       `json:"synced_at"`
+	Added        []CardChange `json:"added"`
+	Changed      []CardChange `json:"changed"`
+	Removed      []CardChange `json:"removed"`
+}
+
+type UpstreamMergeResult struct {
+	Added     int          `json:"added"`
+	Updated   int          `json:"updated"`
+	Removed   int          `json:"removed"`
+	Conflicts []CardChange `json:"conflicts"`
+}
+
+const (
+	ClassRoleTeacher = "teacher"
+	ClassRoleStudent = "student"
+)
+
+type Classroom struct {
+	ID        int    `json:"id"`
+	OwnerID   int    `json:"owner_id"`
 	Name      string `json:"name"`
+	JoinCode  string `json:"join_code,omitempty"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+type Assignment struct {
+	ClassroomID int     `json:"classroom_id"`
+	DeckID      int     `json:"deck_id"`
+	DeckName    string  `json:"deck_name"`
+	DueAt       *string `json:"due_at,omitempty"`
+	AssignedAt  string  `json:"assigned_at"`
+}
+
+type StudentProgress struct {
+	UserID       int     `json:"user_id"`
+	FirstName    string  `json:"first_name"`
+	LastName     string  `json:"last_name"`
+	Email        string  `json:"email"`
+	DeckID       int     `json:"deck_id"`
+	DueAt        *string `json:"due_at,omitempty"`
+	CardsTotal   int     `json:"cards_total"`
+	CardsStudied int     `json:"cards_studied"`
+	CardsOverdue int     `json:"cards_overdue"`
+	Reviews      int     `json:"reviews"`
+	Retention    float64 `json:"retention"`
+	Late         bool    `json:"late"`
+}
+
+type Group struct {
+	ID         int    `json:"id"`
+	OwnerID    int    `json:"owner_id"`
+	Name       string `json:"name"`
+	InviteCode string `json:"invite_code"`
+	Hidden     bool   `json:"hidden"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type GroupInvite struct {
+	GroupID   int    `json:"group_id"`
+	GroupName string `json:"group_name"`
+	InvitedBy int    `json:"invited_by"`
 	CreatedAt string `json:"created_at"`
 }
+
+type LeaderboardEntry struct {
+	Rank      int     `json:"rank"`
+	UserID    int     `json:"user_id"`
+	FirstName string  `json:"first_name"`
+	LastName  string  `json:"last_name"`
+	Reviews   int     `json:"reviews"`
+	Accuracy  float64 `json:"accuracy"`
+	Streak    int     `json:"streak"`
+}
+
+type Leaderboard struct {
+	GroupID   int                `json:"group_id"`
+	WeekStart string             `json:"week_start"`
+	WeekEnd   string             `json:"week_end"`
+	SortBy    string             `json:"sort_by"`
+	Entries   []LeaderboardEntry `json:"entries"`
+}
+
+const (
+	ChallengeMetricReviews = "reviews"
+	ChallengeMetricCorrect = "correct"
+	ChallengeMetricDays    = "days"
+)
+
+type Challenge struct {
+	ID        int    `json:"id"`
+	GroupID   int    `json:"group_id"`
+	CreatedBy int    `json:"created_by"`
+	Title     string `json:"title"`
+	Metric    string `json:"metric"`
+	Target    int    `json:"target"`
+	StartsAt  string `json:"starts_at"`
+	EndsAt    string `json:"ends_at"`
+}
+
+type ChallengeMemberProgress struct {
+	UserID    int    `json:"user_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Value     int    `json:"value"`
+	Completed bool   `json:"completed"`
+}
+
+type ChallengeProgress struct {
+	Challenge
+	Active  bool                      `json:"active"`
+	Total   int                       `json:"total"`
+	Members []ChallengeMemberProgress `json:"members"`
+}
+
+// ImportState is scheduling state carried over from another application.
+type ImportState struct {
+	DueAt        time.Time
+	IntervalDays int
+	Ease         float64
+	Reps         int
+	Lapses       int
+}
+
+type ImportCard struct {
+	Question string
+	Answer   string
+	Tags     []string
+	State    *ImportState
+}
+
+// ImportDeck is a group of imported cards. When ID is set the cards go into
+// that existing deck; otherwise into the user's deck called Name, which is
+// created if needed.
+type ImportDeck struct {
+	ID    int
+	Name  string
+	Cards []ImportCard
+}
+
+type ImportIssue struct {
+	Item   string `json:"item"`
+	Reason string `json:"reason"`
+}
+
+type ImportedDeck struct {
+	DeckID     int    `json:"deck_id"`
+	Name       string `json:"name"`
+	Created    bool   `json:"created"`
+	Imported   int    `json:"imported"`
+	Duplicates int    `json:"duplicates"`
+}
+
+// Progress is told how many of a long operation's items are done, after each
+// one. A nil Progress is not called.
+type Progress func(done, total int)
+
+type ImportReport struct {
+	Decks        []ImportedDeck `json:"decks"`
+	Imported     int            `json:"imported"`
+	Duplicates   int            `json:"duplicates"`
+	Skipped      []ImportIssue  `json:"skipped"`
+	Warnings     []ImportIssue  `json:"warnings,omitempty"`
+	MediaSkipped int            `json:"media_skipped,omitempty"`
+}
+
+type CSVColumn struct {
+	Index   int      `json:"index"`
+	Header  string   `json:"header,omitempty"`
+	Samples []string `json:"samples"`
+	Guess   string   `json:"guess"`
+}
+
+type CSVPreview struct {
+	UploadID  string      `json:"upload_id"`
+	Filename  string      `json:"filename"`
+	Delimiter string      `json:"delimiter"`
+	HasHeader bool        `json:"has_header"`
+	Rows      int         `json:"rows"`
+	Columns   []CSVColumn `json:"columns"`
+}
+
+// CSVMapping assigns file columns (by zero-based index) to card fields.
+type CSVMapping struct {
+	Question int  `json:"question"`
+	Answer   int  `json:"answer"`
+	Tags     *int `json:"tags,omitempty"`
+	Deck     *int `json:"deck,omitempty"`
+}
+
+type ExportCard struct {
+	ID        int        `json:"id"`
+	Question  string     `json:"question"`
+	Answer    string     `json:"answer"`
+	Tags      []string   `json:"tags"`
+	CreatedAt string     `json:"created_at"`
+	State     *CardState `json:"state,omitempty"`
+}
+
+type ExportDeck struct {
+	ID        int          `json:"id"`
+	Name      string       `json:"name"`
+	CreatedAt string       `json:"created_at"`
+	Cards     []ExportCard `json:"cards"`
+}
+
+// SyncCard is a card read from a deck file. A non-zero ID refers to the card
+// the file was exported from.
+type SyncCard struct {
+	ID       int
+	Question string
+	Answer   string
+	Tags     []string
+}
+
+type SyncReport struct {
+	DeckID    int           `json:"deck_id"`
+	Name      string        `json:"name"`
+	Created   int           `json:"created"`
+	Updated   int           `json:"updated"`
+	Unchanged int           `json:"unchanged"`
+	Deleted   int           `json:"deleted"`
+	Skipped   []ImportIssue `json:"skipped"`
+}
+
+const (
+	JobImport = "import"
+	JobExport = "export"
+)
+
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Job is a background import or export. Result holds the import report once
+// the job is done; exports produce a downloadable artifact instead.
+type Job struct {
+	ID           string          `json:"id"`
+	Kind         string          `json:"kind"`
+	Format       string          `json:"format"`
+	Status       string          `json:"status"`
+	Progress     int             `json:"progress"`
+	Result       json.RawMessage `json:"result,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	ArtifactName string          `json:"artifact_name,omitempty"`
+	CreatedAt    string          `json:"created_at"`
+	StartedAt    *string         `json:"started_at,omitempty"`
+	FinishedAt   *string         `json:"finished_at,omitempty"`
+}
+
+// JobParams are the options a job was queued with.
+type JobParams struct {
+	Format        string          `json:"format"`
+	DeckID        int             `json:"deck_id,omitempty"`
+	DeckName      string          `json:"deck_name,omitempty"`
+	Filename      string          `json:"filename,omitempty"`
+	Scheduling    bool            `json:"scheduling,omitempty"`
+	Trim          bool            `json:"trim,omitempty"`
+	Prune         bool            `json:"prune,omitempty"`
+	TermSeparator string          `json:"term_separator,omitempty"`
+	CardSeparator string          `json:"card_separator,omitempty"`
+	Duplicates    DuplicatePolicy `json:"duplicates,omitempty"`
+}
+
+// JobTask is a claimed job as handed to a worker.
+type JobTask struct {
+	ID     string
+	UserID int
+	Kind   string
+	Params JobParams
+	Input  []byte
+}
+
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+	BulkMove   = "move"
+)
+
+// BulkOperation is one item of a bulk card request. Updates leave tags as
+// they are when Tags is omitted.
+type BulkOperation struct {
+	Op       string   `json:"op"`
+	CardID   int      `json:"card_id,omitempty"`
+	DeckID   int      `json:"deck_id,omitempty"`
+	Question string   `json:"question,omitempty"`
+	Answer   string   `json:"answer,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+type BulkResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	OK     bool   `json:"ok"`
+	CardID int    `json:"card_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CardTransfer reports where a moved or copied card ended up. NewCardID is
+// set for copies.
+type CardTransfer struct {
+	CardID    int `json:"card_id"`
+	NewCardID int `json:"new_card_id,omitempty"`
+	DeckID    int `json:"deck_id"`
+}
+
+const (
+	DuplicatesAllow  = "allow"
+	DuplicatesWarn   = "warn"
+	DuplicatesReject = "reject"
+)
+
+const (
+	DuplicateScopeDeck       = "deck"
+	DuplicateScopeCollection = "collection"
+)
+
+// DuplicatePolicy says how new cards that duplicate existing ones are
+// handled. Similarity between 0 and 1 also matches near duplicates; otherwise
+// only identical normalized questions match.
+type DuplicatePolicy struct {
+	Mode       string  `json:"mode,omitempty"`
+	Scope      string  `json:"scope,omitempty"`
+	Similarity float64 `json:"similarity,omitempty"`
+}
+
+type DuplicateCard struct {
+	ID       int    `json:"id"`
+	DeckID   int    `json:"deck_id"`
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+type DuplicateMatch struct {
+	DuplicateCard
+	Similarity float64 `json:"similarity"`
+	Exact      bool    `json:"exact"`
+}
+
+type DuplicateGroup struct {
+	Exact bool            `json:"exact"`
+	Cards []DuplicateCard `json:"cards"`
+}
+
+// CardRevision records one edit of a card's question or answer. Revisions are
+// numbered from 1 per card.
+type CardRevision struct {
+	Rev            int        `json:"rev"`
+	CardID         int        `json:"card_id"`
+	UserID         int        `json:"user_id"`
+	UserName       string     `json:"user_name"`
+	QuestionBefore string     `json:"question_before"`
+	AnswerBefore   string     `json:"answer_before"`
+	QuestionAfter  string     `json:"question_after"`
+	AnswerAfter    string     `json:"answer_after"`
+	CreatedAt      string     `json:"created_at"`
+	QuestionDiff   []DiffLine `json:"question_diff"`
+	AnswerDiff     []DiffLine `json:"answer_diff"`
+}
+
+const (
+	DiffEqual  = "="
+	DiffInsert = "+"
+	DiffDelete = "-"
+)
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Trash lists the decks and cards a user can restore. PurgeAt is when an item
+// is removed permanently.
+type Trash struct {
+	Decks []TrashedDeck `json:"decks"`
+	Cards []TrashedCard `json:"cards"`
+}
+
+type TrashedDeck struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	CardCount int    `json:"card_count"`
+	DeletedAt string `json:"deleted_at"`
+	PurgeAt   string `json:"purge_at"`
+}
+
+type TrashedCard struct {
+	ID        int    `json:"id"`
+	DeckID    int    `json:"deck_id"`
+	DeckName  string `json:"deck_name"`
+	Question  string `json:"question"`
+	Answer    string `json:"answer"`
+	DeletedAt string `json:"deleted_at"`
+	PurgeAt   string `json:"purge_at"`
+}
+
+// ItemPosition is where a deck or card sits in its list after a reorder.
+type ItemPosition struct {
+	ID       int   `json:"id"`
+	Position int64 `json:"position"`
+}
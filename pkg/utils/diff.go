@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"go-flashcards-server/pkg/types"
+	"strings"
+)
+
+// DiffLines compares before and after line by line using their longest
+// common subsequence.
+func DiffLines(before, after string) []types.DiffLine {
+	a, b := strings.Split(before, "\n"), strings.Split(after, "\n")
+
+	// common[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	lines := []types.DiffLine{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, types.DiffLine{Op: types.DiffEqual, Text: a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, types.DiffLine{Op: types.DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, types.DiffLine{Op: types.DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	return lines
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"go-flashcards-server/pkg/types"
 	"net/http"
+	"strings"
 )
 
 func HandleErrorResponse(w http.ResponseWriter, message string, code int) {
@@ -16,3 +17,19 @@ func HandleErrorResponse(w http.ResponseWriter, message string, code int) {
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(response)
 }
+
+// NormalizeTags lowercases and de-duplicates tags, dropping empty ones and
+// joining inner whitespace with dashes.
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}